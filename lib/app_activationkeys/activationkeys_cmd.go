@@ -0,0 +1,345 @@
+package app_activationkeys
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/thoas/go-funk"
+	"github.com/urfave/cli"
+	"sort"
+	"strings"
+)
+
+var ActivationKeysCmdFlags []cli.Flag
+
+func init() {
+	ActivationKeysCmdFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:   "l, list",
+			Usage:  "list activation keys",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "create",
+			Usage:  "create an activation key",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "clone",
+			Usage: "clone the activation key with the description, specified by --description",
+		},
+		cli.BoolFlag{
+			Name:   "retarget",
+			Usage:  "point the activation key to another base and/or child channels",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "rewrite",
+			Usage:  "rewrite all activation keys from channels of one phase to another",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "k, key",
+			Usage: "activation key to create or retarget",
+		},
+		cli.StringFlag{
+			Name:  "d, description",
+			Usage: "description of the activation key",
+		},
+		cli.StringFlag{
+			Name:  "b, base-channel",
+			Usage: "base channel of the activation key",
+		},
+		cli.StringFlag{
+			Name:  "c, child-channels",
+			Usage: "comma-separated list of child channels of the activation key",
+		},
+		cli.IntFlag{
+			Name:  "u, usage-limit",
+			Usage: "usage limit of the activation key, unlimited if not set",
+		},
+		cli.StringFlag{
+			Name:  "from-phase",
+			Usage: "phase of the channels to rewrite activation keys from",
+		},
+		cli.StringFlag{
+			Name:  "to-phase",
+			Usage: "phase of the channels to rewrite activation keys to",
+		},
+		cli.StringFlag{
+			Name:  "w, workflow",
+			Usage: "use configured workflow",
+		},
+		cli.BoolFlag{
+			Name:   "n, dry-run",
+			Usage:  "don't perform any real operations",
+			Hidden: false,
+		},
+	}
+}
+
+type activationKeysCmd struct {
+	workflow *utils.Workflow
+	channels utils.ChannelLabels
+	ctx      *cli.Context
+}
+
+// NewActivationKeysCmd constructor
+func NewActivationKeysCmd(ctx *cli.Context) *activationKeysCmd {
+	cmd := new(activationKeysCmd)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// Get all activation keys
+func (cmd *activationKeysCmd) activationKeys() []map[string]interface{} {
+	keys, err := utils.AsStructs(utils.RPC.RequestFuction("activationkey.listActivationKeys"))
+	utils.Console.CheckError(err)
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]["key"]) < fmt.Sprint(keys[j]["key"])
+	})
+	return keys
+}
+
+// Get base channel label of the activation key or an empty string, if key has none
+func (cmd *activationKeysCmd) baseChannel(key map[string]interface{}) string {
+	label, _ := key["base_channel_label"].(string)
+	if label == "none" {
+		label = ""
+	}
+	return label
+}
+
+// Get child channel labels of the activation key
+func (cmd *activationKeysCmd) childChannels(key map[string]interface{}) []string {
+	labels := make([]string, 0)
+	if children, ok := key["child_channel_labels"].([]interface{}); ok {
+		for _, label := range children {
+			labels = append(labels, fmt.Sprint(label))
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// ListActivationKeys prints all activation keys with their channels
+func (cmd *activationKeysCmd) ListActivationKeys() {
+	rows := make([][]interface{}, 0)
+	for _, key := range cmd.activationKeys() {
+		var base, children, limit interface{}
+		if label := cmd.baseChannel(key); label != "" {
+			base = label
+		}
+		if labels := cmd.childChannels(key); len(labels) > 0 {
+			children = labels
+		}
+		if usage, ok := key["usage_limit"].(int); ok && usage > 0 {
+			limit = usage
+		}
+		rows = append(rows, []interface{}{key["key"], key["description"], base, children, limit})
+	}
+	if len(rows) == 0 {
+		utils.Console.ExitOnStderr("No activation keys has been found")
+	}
+	fmt.Println()
+	outputters.NewAnsiCLI().Table([]string{"Key", "Description", "Base channel", "Child channels", "Usage limit"}, rows)
+}
+
+// CreateActivationKey creates a new activation key with the base and child channels
+func (cmd *activationKeysCmd) CreateActivationKey(key string, description string, base string, children []string) {
+	utils.Log.Info("Creating activation key \"%s\" with base channel \"%s\"", key, base)
+	if cmd.ctx.Bool("dry-run") {
+		return
+	}
+	var created string
+	var err error
+	if limit := cmd.ctx.Int("usage-limit"); limit > 0 {
+		created, err = utils.AsString(utils.RPC.RequestFuction("activationkey.create", key, description, base, limit, []string{}, false))
+	} else {
+		created, err = utils.AsString(utils.RPC.RequestFuction("activationkey.create", key, description, base, []string{}, false))
+	}
+	if err == nil {
+		key = created
+		if len(children) > 0 {
+			_, err = utils.RPC.RequestFuction("activationkey.addChildChannels", key, children)
+		}
+	}
+	channels := children
+	if base != "" {
+		channels = append([]string{base}, children...)
+	}
+	utils.Audit.Record(utils.AuditEntry{Workflow: cmd.workflow.Name(), Operation: "create", Source: strings.Join(channels, ","),
+		Target: fmt.Sprintf("activation key %s", key), Counts: map[string]int{"channels": len(channels)}}, err)
+	utils.Console.CheckError(err)
+	fmt.Printf("Activation key \"%s\" created\n", key)
+}
+
+// CloneActivationKey clones the activation key with all its settings
+func (cmd *activationKeysCmd) CloneActivationKey(key string, description string) {
+	if description == "" {
+		description = fmt.Sprintf("Clone of %s", key)
+	}
+	utils.Log.Info("Cloning activation key \"%s\"", key)
+	if cmd.ctx.Bool("dry-run") {
+		return
+	}
+	clone, err := utils.AsString(utils.RPC.RequestFuction("activationkey.clone", key, description))
+	utils.Audit.Record(utils.AuditEntry{Workflow: cmd.workflow.Name(), Operation: "clone", Source: fmt.Sprintf("activation key %s", key),
+		Target: fmt.Sprintf("activation key %s", clone)}, err)
+	if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+		utils.Console.ExitOnStderr(fmt.Sprintf("Activation key \"%s\" does not exist", key))
+	}
+	utils.Console.CheckError(err)
+	fmt.Printf("Activation key \"%s\" cloned to \"%s\"\n", key, clone)
+}
+
+// RetargetActivationKey points the activation key to the base channel (if not empty) and child channels (if not nil)
+func (cmd *activationKeysCmd) RetargetActivationKey(key string, base string, children []string) error {
+	for _, label := range append([]string{base}, children...) {
+		if label != "" && !cmd.channels.Exists(label) {
+			return fmt.Errorf("Channel \"%s\" does not exist", label)
+		}
+	}
+
+	details, err := utils.AsStruct(utils.RPC.RequestFuction("activationkey.getDetails", key))
+	if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+		return fmt.Errorf("Activation key \"%s\" does not exist", key)
+	} else if err != nil {
+		return err
+	}
+	if base != "" {
+		utils.Log.Info("Setting base channel of activation key \"%s\" to \"%s\"", key, base)
+		if !cmd.ctx.Bool("dry-run") {
+			_, err := utils.RPC.RequestFuction("activationkey.setDetails", key, map[string]interface{}{"base_channel_label": base})
+			if err := cmd.audit(key, []string{base}, err); err != nil {
+				return err
+			}
+		}
+	}
+	if children != nil {
+		utils.Log.Info("Setting child channels of activation key \"%s\" to \"%s\"", key, strings.Join(children, "\", \""))
+		if !cmd.ctx.Bool("dry-run") {
+			if err := cmd.audit(key, children, cmd.setChildChannels(key, cmd.childChannels(details), children)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replace current child channels of the activation key
+func (cmd *activationKeysCmd) setChildChannels(key string, current []string, children []string) error {
+	if len(current) > 0 {
+		if _, err := utils.RPC.RequestFuction("activationkey.removeChildChannels", key, current); err != nil {
+			return err
+		}
+	}
+	if len(children) > 0 {
+		if _, err := utils.RPC.RequestFuction("activationkey.addChildChannels", key, children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record subscription of the activation key to the channels in the audit log and pass its error through
+func (cmd *activationKeysCmd) audit(key string, channels []string, err error) error {
+	utils.Audit.Record(utils.AuditEntry{Workflow: cmd.workflow.Name(), Operation: "subscribe", Source: strings.Join(channels, ","),
+		Target: fmt.Sprintf("activation key %s", key), Counts: map[string]int{"channels": len(channels)}}, err)
+	return err
+}
+
+// RewriteActivationKeys points all activation keys of channels in one phase to the same channels of another phase
+func (cmd *activationKeysCmd) RewriteActivationKeys(fromPhase string, toPhase string) {
+	if !funk.ContainsString(cmd.workflow.Phases(), fromPhase) {
+		utils.Console.ExitOnStderr(fmt.Sprintf("Phase \"%s\" is not in workflow \"%s\"", fromPhase, cmd.workflow.Name()))
+	}
+
+	rewritten := 0
+	for _, key := range cmd.activationKeys() {
+		name := fmt.Sprint(key["key"])
+		base := cmd.baseChannel(key)
+		if base == "" || cmd.workflow.PhaseOf(base) != fromPhase {
+			continue
+		}
+
+		targetBase, err := cmd.workflow.Relabel(base, toPhase)
+		utils.Console.CheckError(err)
+		targetChildren := make([]string, 0)
+		for _, child := range cmd.childChannels(key) {
+			if cmd.workflow.PhaseOf(child) == fromPhase {
+				child, err = cmd.workflow.Relabel(child, toPhase)
+				utils.Console.CheckError(err)
+			}
+			targetChildren = append(targetChildren, child)
+		}
+
+		missing := make([]string, 0)
+		for _, label := range append([]string{targetBase}, targetChildren...) {
+			if !cmd.channels.Exists(label) {
+				missing = append(missing, label)
+			}
+		}
+		if len(missing) > 0 {
+			utils.Log.Warning("Skipping activation key \"%s\": missing channels \"%s\"", name, strings.Join(missing, "\", \""))
+			continue
+		}
+
+		fmt.Printf("Activation key \"%s\": %s -> %s\n", name, base, targetBase)
+		if err := cmd.RetargetActivationKey(name, targetBase, targetChildren); err != nil {
+			if fault, ok := utils.AsFault(err); ok && fault.IsPermission() {
+				utils.Log.Warning("Skipping activation key \"%s\": %s", name, err.Error())
+				continue
+			}
+			utils.Console.CheckError(err)
+		}
+		rewritten++
+	}
+	fmt.Printf("Rewritten %d activation keys from phase \"%s\" to \"%s\"\n", rewritten, fromPhase, toPhase)
+}
+
+// Set flags from CLI and configuration about current runtime session
+func (cmd *activationKeysCmd) SetCurrentConfig() *activationKeysCmd {
+	var err error
+	cmd.workflow, err = utils.NewWorkflow(cmd.ctx, cmd.ctx.String("workflow"))
+	utils.Console.CheckError(err)
+	utils.Log.Debug("Configuration set")
+
+	return cmd
+}
+
+// Entry action for the activation keys sub-app
+func MainActivationKeysCmd(ctx *cli.Context) error {
+	cmd := NewActivationKeysCmd(ctx).SetCurrentConfig()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+
+	if ctx.Bool("create") || ctx.Bool("retarget") {
+		if ctx.String("key") == "" {
+			utils.Console.ExitOnUnknown("Activation key required.")
+		}
+	}
+
+	if ctx.Bool("list") {
+		cmd.ListActivationKeys()
+	} else if ctx.Bool("create") {
+		cmd.CreateActivationKey(ctx.String("key"), ctx.String("description"), ctx.String("base-channel"), utils.SplitList(ctx.String("child-channels")))
+	} else if ctx.String("clone") != "" {
+		cmd.CloneActivationKey(ctx.String("clone"), ctx.String("description"))
+	} else if ctx.Bool("retarget") {
+		var children []string
+		if ctx.IsSet("child-channels") {
+			children = utils.SplitList(ctx.String("child-channels"))
+		}
+		utils.Console.CheckError(cmd.RetargetActivationKey(ctx.String("key"), ctx.String("base-channel"), children))
+	} else if ctx.Bool("rewrite") {
+		if ctx.String("from-phase") == "" || ctx.String("to-phase") == "" {
+			utils.Console.ExitOnUnknown("Both phases to rewrite from and to are required.")
+		}
+		cmd.RewriteActivationKeys(ctx.String("from-phase"), ctx.String("to-phase"))
+	} else {
+		utils.Console.ExitOnUnknown("Don't know what to do.")
+	}
+
+	return nil
+}
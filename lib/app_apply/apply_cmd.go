@@ -0,0 +1,339 @@
+package app_apply
+
+import (
+	"fmt"
+	"github.com/aybabtme/rgbterm"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/thoas/go-funk"
+	"github.com/urfave/cli"
+	"sort"
+	"strings"
+)
+
+var ApplyCmdFlags []cli.Flag
+
+func init() {
+	ApplyCmdFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:   "prune",
+			Usage:  "delete undeclared child channels of the declared trees and undeclared channel trees within --prune-prefix",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "prune-prefix",
+			Usage: "also report or delete undeclared base channels with the label `prefix`, together with their children",
+		},
+		cli.BoolFlag{
+			Name:   "n, dry-run",
+			Usage:  "only report the drift, don't perform any real operations",
+			Hidden: false,
+		},
+	}
+}
+
+// Declared channel
+type channelSpec struct {
+	label       string
+	name        string
+	summary     string
+	description string
+	arch        string
+	checksum    string
+	parent      string
+	repos       []string
+	declared    map[string]bool
+}
+
+type applyCmd struct {
+	specs    []*channelSpec
+	existing map[string]string
+	drift    int
+	api      *uyuni.Client
+	ctx      *cli.Context
+}
+
+// NewApplyCmd constructor
+func NewApplyCmd(ctx *cli.Context) *applyCmd {
+	cmd := new(applyCmd)
+	cmd.ctx = ctx
+	cmd.api = uyuni.NewClient(utils.RPC)
+	cmd.specs = make([]*channelSpec, 0)
+	return cmd
+}
+
+// LoadChannels reads declared channels from the YAML file
+func (cmd *applyCmd) LoadChannels(filename string) *applyCmd {
+	data, err := utils.Configuration.ReadYaml(filename)
+	utils.Console.CheckError(err)
+	channels, err := data.Get("channels").Array()
+	if err != nil {
+		utils.Console.ExitOnStderr(fmt.Sprintf("File \"%s\" should contain \"channels\" list", filename))
+	}
+
+	for idx, channelData := range channels {
+		channel, ok := channelData.(map[interface{}]interface{})
+		if !ok {
+			utils.Console.ExitOnStderr(fmt.Sprintf("Channel #%d: structure expected", idx+1))
+		}
+		value := func(key string, defaultValue string) string {
+			if data, exist := channel[key]; exist && data != nil {
+				return fmt.Sprint(data)
+			}
+			return defaultValue
+		}
+
+		spec := &channelSpec{
+			label:    value("label", ""),
+			parent:   value("parent", ""),
+			arch:     value("arch", "channel-x86_64"),
+			checksum: value("checksum", "sha256"),
+			repos:    make([]string, 0),
+			declared: make(map[string]bool),
+		}
+		for key, data := range channel {
+			if data != nil {
+				spec.declared[fmt.Sprint(key)] = true
+			}
+		}
+		if spec.label == "" {
+			utils.Console.ExitOnStderr(fmt.Sprintf("Channel #%d: label is missing", idx+1))
+		}
+		spec.name = value("name", spec.label)
+		spec.summary = value("summary", spec.name)
+		spec.description = value("description", "")
+		if repos, ok := channel["repos"].([]interface{}); ok {
+			for _, repo := range repos {
+				spec.repos = append(spec.repos, fmt.Sprint(repo))
+			}
+		}
+		cmd.specs = append(cmd.specs, spec)
+	}
+
+	// Parents should be processed before their children
+	sort.SliceStable(cmd.specs, func(i, j int) bool {
+		return cmd.specs[i].parent == "" && cmd.specs[j].parent != ""
+	})
+
+	return cmd
+}
+
+// Get existing channels with their parents
+func (cmd *applyCmd) existingChannels() (map[string]string, error) {
+	if cmd.existing == nil {
+		channels, err := cmd.api.ListSoftwareChannels()
+		if err != nil {
+			return nil, err
+		}
+		cmd.existing = make(map[string]string)
+		for _, channel := range channels {
+			cmd.existing[channel.Label] = channel.ParentLabel
+		}
+	}
+	return cmd.existing, nil
+}
+
+// Report a difference between the declared and actual state
+func (cmd *applyCmd) report(mark string, message string, args ...interface{}) {
+	cmd.drift++
+	colors := map[string][]uint8{"+": {0x40, 0xff, 0x40}, "-": {0xff, 0x40, 0x40}, "~": {0xff, 0xff, 0}, "!": {0xff, 0x80, 0}}
+	color := colors[mark]
+	fmt.Printf("%s %s\n", rgbterm.FgString(mark, color[0], color[1], color[2]), fmt.Sprintf(message, args...))
+}
+
+// Create declared channel
+func (cmd *applyCmd) createChannel(spec *channelSpec) error {
+	cmd.report("+", "create channel \"%s\"", spec.label)
+	if cmd.ctx.Bool("dry-run") {
+		return nil
+	}
+	err := cmd.api.CreateChannel(spec.label, spec.name, spec.summary, spec.arch, spec.parent, spec.checksum)
+	if err == nil && spec.description != "" {
+		var details *uyuni.ChannelDetails
+		if details, err = cmd.api.ChannelDetails(spec.label); err == nil {
+			err = cmd.api.SetChannelDetails(details.ID, map[string]interface{}{"description": spec.description})
+		}
+	}
+	utils.Audit.Record(utils.AuditEntry{Operation: "create", Target: spec.label}, err)
+	return err
+}
+
+// Update metadata of the existing channel to the declared one
+func (cmd *applyCmd) updateChannel(spec *channelSpec) error {
+	details, err := cmd.api.ChannelDetails(spec.label)
+	if err != nil {
+		return err
+	}
+	actual := func(key string) string {
+		if data := details.Fields()[key]; data != nil {
+			return fmt.Sprint(data)
+		}
+		return ""
+	}
+
+	// Options, which are not declared in the file, are left unchanged
+	changes := make(map[string]interface{})
+	for _, field := range [][]string{{"name", "name", spec.name}, {"summary", "summary", spec.summary},
+		{"description", "description", spec.description}, {"checksum", "checksum_label", spec.checksum}} {
+		if !spec.declared[field[0]] {
+			continue
+		}
+		if current := actual(field[1]); current != field[2] {
+			cmd.report("~", "channel \"%s\": %s \"%s\" -> \"%s\"", spec.label, field[1], current, field[2])
+			changes[field[1]] = field[2]
+		}
+	}
+	if current := actual("arch_label"); spec.declared["arch"] && current != spec.arch {
+		cmd.report("!", "channel \"%s\": architecture is \"%s\" instead of \"%s\" and cannot be changed", spec.label, current, spec.arch)
+	}
+	if current := actual("parent_channel_label"); current != spec.parent {
+		cmd.report("!", "channel \"%s\": parent is \"%s\" instead of \"%s\" and cannot be changed", spec.label, current, spec.parent)
+	}
+
+	if len(changes) > 0 && !cmd.ctx.Bool("dry-run") {
+		err = cmd.api.SetChannelDetails(details.ID, changes)
+		utils.Audit.Record(utils.AuditEntry{Operation: "update", Target: spec.label, Counts: map[string]int{"fields": len(changes)}}, err)
+	}
+	return err
+}
+
+// Associate declared repositories with the channel and disassociate undeclared ones, if pruning
+func (cmd *applyCmd) applyRepos(spec *channelSpec, created bool) error {
+	associated := make([]string, 0)
+	if !created {
+		repos, err := cmd.api.ListChannelRepos(spec.label)
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			associated = append(associated, repo.Label)
+		}
+	}
+
+	for _, repo := range spec.repos {
+		if !funk.ContainsString(associated, repo) {
+			cmd.report("+", "associate repository \"%s\" with channel \"%s\"", repo, spec.label)
+			if !cmd.ctx.Bool("dry-run") {
+				if err := cmd.api.AssociateRepo(spec.label, repo); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, repo := range associated {
+		if funk.ContainsString(spec.repos, repo) {
+			continue
+		}
+		if cmd.ctx.Bool("prune") {
+			cmd.report("-", "disassociate repository \"%s\" from channel \"%s\"", repo, spec.label)
+			if !cmd.ctx.Bool("dry-run") {
+				if err := cmd.api.DisassociateRepo(spec.label, repo); err != nil {
+					return err
+				}
+			}
+		} else {
+			cmd.report("!", "repository \"%s\" of channel \"%s\" is not declared", repo, spec.label)
+		}
+	}
+	return nil
+}
+
+/*
+Delete or report channels, which are not declared: children of the declared trees
+and, with --prune-prefix, base channels with the prefix together with all their children.
+Other undeclared base channels are never touched.
+*/
+func (cmd *applyCmd) pruneChannels() error {
+	existing, err := cmd.existingChannels()
+	if err != nil {
+		return err
+	}
+	declared := make([]string, len(cmd.specs))
+	for idx, spec := range cmd.specs {
+		declared[idx] = spec.label
+	}
+	prefix := cmd.ctx.String("prune-prefix")
+	inScope := func(base string) bool {
+		return funk.ContainsString(declared, base) || prefix != "" && strings.HasPrefix(base, prefix)
+	}
+
+	undeclared := make([]string, 0)
+	for label, parent := range existing {
+		if funk.ContainsString(declared, label) {
+			continue
+		}
+		if parent != "" && inScope(parent) || parent == "" && inScope(label) {
+			undeclared = append(undeclared, label)
+		}
+	}
+	// Children should be deleted before their parents
+	sort.Slice(undeclared, func(i, j int) bool {
+		iChild, jChild := existing[undeclared[i]] != "", existing[undeclared[j]] != ""
+		if iChild != jChild {
+			return iChild
+		}
+		return undeclared[i] < undeclared[j]
+	})
+
+	for _, label := range undeclared {
+		if !cmd.ctx.Bool("prune") {
+			cmd.report("!", "channel \"%s\" is not declared", label)
+			continue
+		}
+		cmd.report("-", "delete channel \"%s\"", label)
+		if !cmd.ctx.Bool("dry-run") {
+			err := cmd.api.DeleteChannel(label)
+			utils.Audit.Record(utils.AuditEntry{Operation: "delete", Target: label}, err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Apply makes the server channels match the declared ones
+func (cmd *applyCmd) Apply() error {
+	existing, err := cmd.existingChannels()
+	if err != nil {
+		return err
+	}
+	for _, spec := range cmd.specs {
+		_, exists := existing[spec.label]
+		if exists {
+			utils.Log.Debug("Checking channel \"%s\"", spec.label)
+			err = cmd.updateChannel(spec)
+		} else {
+			err = cmd.createChannel(spec)
+		}
+		if err == nil {
+			err = cmd.applyRepos(spec, !exists)
+		}
+		if err != nil {
+			return fmt.Errorf("Channel \"%s\": %s", spec.label, err.Error())
+		}
+	}
+	if err := cmd.pruneChannels(); err != nil {
+		return err
+	}
+
+	if cmd.drift == 0 {
+		fmt.Println("Channels are up to date")
+	} else if cmd.ctx.Bool("dry-run") {
+		fmt.Printf("Found %d differences, nothing has been changed\n", cmd.drift)
+	}
+	return nil
+}
+
+// Entry action for the apply sub-app
+func MainApplyCmd(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Console.ExitOnUnknown("File with declared channels required.")
+	}
+	cmd := NewApplyCmd(ctx).LoadChannels(ctx.Args().First())
+	utils.RefreshCache()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	utils.Console.CheckError(cmd.Apply())
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package app_apply
+
+import (
+	"flag"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// State of the fake server: "sles" base channel with all metadata set
+const testState = `{
+  "channels": [
+    {"id": 1, "label": "sles", "name": "SLES", "summary": "Base", "description": "Keep me", "arch_label": "channel-x86_64",
+     "checksum_label": "sha1", "packages": [], "errata": []}
+  ],
+  "last_id": 100
+}`
+
+func TestApplyKeepsUndeclaredOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spaceman-apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+	config := filepath.Join(dir, "config.conf")
+	channels := filepath.Join(dir, "channels.yaml")
+	for filename, content := range map[string]string{
+		stateFile: testState,
+		config:    "server:\n  backend: fake\n  user: admin\n  state_file: " + stateFile + "\n",
+		channels:  "channels:\n  - label: sles\n    name: SLES 15\n",
+	} {
+		if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	utils.Audit.SetFile(filepath.Join(dir, "audit.log"))
+
+	app := cli.NewApp()
+	globalSet := flag.NewFlagSet("spaceman", flag.ContinueOnError)
+	globalSet.String("config", "", "")
+	globalSet.String("server", "", "")
+	if err := globalSet.Parse([]string{"--config", config}); err != nil {
+		t.Fatal(err)
+	}
+	set := flag.NewFlagSet("apply", flag.ContinueOnError)
+	for _, applyFlag := range ApplyCmdFlags {
+		applyFlag.Apply(set)
+	}
+	ctx := cli.NewContext(app, set, cli.NewContext(app, globalSet, nil))
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+
+	if err := NewApplyCmd(ctx).LoadChannels(channels).Apply(); err != nil {
+		t.Fatal(err)
+	}
+	details, err := uyuni.NewClient(utils.RPC).ChannelDetails("sles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range [][]string{{"name", "SLES 15"}, {"summary", "Base"}, {"description", "Keep me"},
+		{"checksum_label", "sha1"}} {
+		if actual := details.Fields()[field[0]]; actual != field[1] {
+			t.Errorf("%s: expected \"%s\", got \"%v\"", field[0], field[1], actual)
+		}
+	}
+}
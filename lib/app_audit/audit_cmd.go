@@ -0,0 +1,135 @@
+package app_audit
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/thoas/go-funk"
+	"github.com/urfave/cli"
+	"sort"
+	"strings"
+	"time"
+)
+
+var AuditCmdFlags []cli.Flag
+
+func init() {
+	AuditCmdFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "c, channel",
+			Usage: "only operations with the channel as a source or a target",
+		},
+		cli.StringFlag{
+			Name:  "u, user",
+			Usage: "only operations of the local operator or the server user",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "only operations since the date (YYYY-MM-DD)",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "only operations until the date (YYYY-MM-DD), inclusive",
+		},
+	}
+}
+
+// Layout of the dates in the filter
+const dateLayout = "2006-01-02"
+
+type auditCmd struct {
+	channel string
+	user    string
+	since   time.Time
+	until   time.Time
+	ctx     *cli.Context
+}
+
+// NewAuditCmd constructor
+func NewAuditCmd(ctx *cli.Context) *auditCmd {
+	cmd := new(auditCmd)
+	cmd.ctx = ctx
+	cmd.channel = ctx.String("channel")
+	cmd.user = ctx.String("user")
+	cmd.since = parseDate("since", ctx.String("since"))
+	if until := parseDate("until", ctx.String("until")); !until.IsZero() {
+		cmd.until = until.AddDate(0, 0, 1)
+	}
+	return cmd
+}
+
+// Parse the date of the filter in the local time
+func parseDate(flag string, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	date, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		utils.Console.ExitOnUnknown(fmt.Sprintf("Option --%s should be a date as YYYY-MM-DD.", flag))
+	}
+	return date
+}
+
+// Check, if the entry passes the filter
+func (cmd *auditCmd) matches(entry utils.AuditEntry) bool {
+	if cmd.channel != "" && entry.Target != cmd.channel && !funk.ContainsString(strings.Split(entry.Source, ","), cmd.channel) {
+		return false
+	}
+	if cmd.user != "" && entry.Operator != cmd.user && entry.User != cmd.user {
+		return false
+	}
+	if !cmd.since.IsZero() && entry.Time.Before(cmd.since) {
+		return false
+	}
+	if !cmd.until.IsZero() && !entry.Time.Before(cmd.until) {
+		return false
+	}
+	return true
+}
+
+// Format counts of the entry, e.g. "errata=3 packages=120"
+func formatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]string, len(names))
+	for idx, name := range names {
+		out[idx] = fmt.Sprintf("%s=%d", name, counts[name])
+	}
+	return strings.Join(out, " ")
+}
+
+// ShowEntries prints recorded operations, which pass the filter
+func (cmd *auditCmd) ShowEntries() {
+	entries, err := utils.Audit.Entries()
+	utils.Console.CheckError(err)
+
+	rows := make([][]interface{}, 0)
+	for _, entry := range entries {
+		if !cmd.matches(entry) {
+			continue
+		}
+		result := entry.Result
+		if entry.Error != "" {
+			result = fmt.Sprintf("%s: %s", result, entry.Error)
+		}
+		rows = append(rows, []interface{}{entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Operator,
+			fmt.Sprintf("%s@%s", entry.User, entry.Server), entry.Workflow, entry.Operation, entry.Source, entry.Target,
+			formatCounts(entry.Counts), result})
+	}
+	if len(rows) == 0 {
+		utils.Console.ExitOnStderr("No operations has been found")
+	}
+	fmt.Println()
+	outputters.NewAnsiCLI().Table([]string{"Time", "Operator", "User@Server", "Workflow", "Operation", "Source", "Target",
+		"Counts", "Result"}, rows)
+}
+
+// Entry action for the audit sub-app
+func MainAuditCmd(ctx *cli.Context) error {
+	NewAuditCmd(ctx).ShowEntries()
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package app_config
+
+import (
+	"github.com/isbm/spaceman/lib/utils"
+	"testing"
+)
+
+func TestFormatValueMasksSecrets(t *testing.T) {
+	for _, test := range []struct {
+		option   utils.ConfigOption
+		expected string
+	}{
+		{utils.ConfigOption{Path: "server.password", Value: "secret"}, masked},
+		{utils.ConfigOption{Path: "server.password_cmd", Value: "pass show uyuni"}, masked},
+		{utils.ConfigOption{Path: "servers.prod.api_token", Value: "abc"}, masked},
+		{utils.ConfigOption{Path: "server.proxy", Value: "http://bob:pw@proxy:3128"}, "http://" + masked + "@proxy:3128"},
+		{utils.ConfigOption{Path: "server.url", Value: "https://uyuni/rpc/api"}, "https://uyuni/rpc/api"},
+		{utils.ConfigOption{Path: "server.user", Value: "admin"}, "admin"},
+		{utils.ConfigOption{Path: "lifecycle.phases", Value: []interface{}{"dev", "prod"}}, "[dev, prod]"},
+	} {
+		if actual := formatValue(test.option); actual != test.expected {
+			t.Errorf("%s: expected \"%s\", got \"%s\"", test.option.Path, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package app_export
+
+import (
+	"encoding/json"
+	"github.com/isbm/spaceman/lib/app_lifecycle"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ExportCmdFlags []cli.Flag
+
+func init() {
+	ExportCmdFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "c, channel",
+			Usage: "channel to export, including its child channels",
+		},
+		cli.BoolFlag{
+			Name:   "e, no-children",
+			Usage:  "skip all child channels",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "o, output",
+			Usage: "write bundle to the file instead of STDOUT",
+		},
+		cli.StringFlag{
+			Name:  "f, format",
+			Usage: "bundle format: json or yaml. Default is taken from the output file extension or json.",
+		},
+	}
+}
+
+// Exported channel with its content
+type channelBundle struct {
+	Label       string                     `json:"label" yaml:"label"`
+	Name        string                     `json:"name" yaml:"name"`
+	Summary     string                     `json:"summary" yaml:"summary"`
+	Description string                     `json:"description,omitempty" yaml:"description,omitempty"`
+	Arch        string                     `json:"arch" yaml:"arch"`
+	Parent      string                     `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Checksum    string                     `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	Original    string                     `json:"original,omitempty" yaml:"original,omitempty"`
+	Packages    []app_lifecycle.PackageRef `json:"packages" yaml:"packages"`
+	Errata      []app_lifecycle.ErratumRef `json:"errata" yaml:"errata"`
+}
+
+// Bundle of the exported channel tree
+type bundle struct {
+	Server   string           `json:"server" yaml:"server"`
+	Exported string           `json:"exported" yaml:"exported"`
+	Channels []*channelBundle `json:"channels" yaml:"channels"`
+}
+
+type exportCmd struct {
+	ctx *cli.Context
+}
+
+// NewExportCmd constructor
+func NewExportCmd(ctx *cli.Context) *exportCmd {
+	cmd := new(exportCmd)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// Get bundle format from the option or file extension
+func bundleFormat(format string, filename string) string {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+	if format == "yml" {
+		format = "yaml"
+	}
+	if format != "yaml" {
+		format = "json"
+	}
+	return format
+}
+
+// Export channel metadata and content
+func (cmd *exportCmd) exportChannel(label string) (*channelBundle, error) {
+	utils.Log.Info("Exporting channel \"%s\"", label)
+	lifecycle := app_lifecycle.NewChannelLifecycle(cmd.ctx).SetCurrentConfig()
+	details, err := lifecycle.GetChannelDetails(label)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := lifecycle.ChannelPackages(label)
+	if err != nil {
+		return nil, err
+	}
+	errata, err := lifecycle.ChannelErrata(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return &channelBundle{
+		Label:       label,
+		Name:        details.Name,
+		Summary:     details.Summary,
+		Description: details.Description,
+		Arch:        details.ArchLabel,
+		Parent:      details.ParentLabel,
+		Checksum:    details.ChecksumLabel,
+		Original:    details.CloneOriginal,
+		Packages:    packages,
+		Errata:      errata,
+	}, nil
+}
+
+// Export channel tree into the bundle
+func (cmd *exportCmd) Export(label string) (*bundle, error) {
+	base, err := cmd.exportChannel(label)
+	if err != nil {
+		return nil, err
+	}
+	data := &bundle{
+		Server:   utils.RPC.GetURL(),
+		Exported: time.Now().Format(time.RFC3339),
+		Channels: []*channelBundle{base},
+	}
+
+	if !cmd.ctx.Bool("no-children") {
+		channels, err := uyuni.NewClient(utils.RPC).ListSoftwareChannels()
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			if channel.ParentLabel == label {
+				child, err := cmd.exportChannel(channel.Label)
+				if err != nil {
+					return nil, err
+				}
+				data.Channels = append(data.Channels, child)
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// Write bundle to the file or STDOUT
+func (cmd *exportCmd) Write(data *bundle, filename string) {
+	var out []byte
+	var err error
+	if bundleFormat(cmd.ctx.String("format"), filename) == "yaml" {
+		out, err = yaml.Marshal(data)
+	} else {
+		out, err = json.MarshalIndent(data, "", "  ")
+		out = append(out, '\n')
+	}
+	utils.Console.CheckError(err)
+
+	if filename == "" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = ioutil.WriteFile(filename, out, 0644)
+	}
+	utils.Console.CheckError(err)
+}
+
+// Entry action for the export sub-app
+func MainExportCmd(ctx *cli.Context) error {
+	if ctx.String("channel") == "" {
+		utils.Console.ExitOnUnknown("Channel required.")
+	}
+	cmd := NewExportCmd(ctx)
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	data, err := cmd.Export(ctx.String("channel"))
+	utils.Console.CheckError(err)
+	cmd.Write(data, ctx.String("output"))
+
+	return nil
+}
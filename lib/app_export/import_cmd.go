@@ -0,0 +1,146 @@
+package app_export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/isbm/spaceman/lib/app_lifecycle"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+var ImportCmdFlags []cli.Flag
+
+func init() {
+	ImportCmdFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:   "C, clear-channel",
+			Usage:  "clear all packages/errata from existing channels before importing",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "f, format",
+			Usage: "bundle format: json or yaml. Default is taken from the file extension or json.",
+		},
+		cli.IntFlag{
+			Name:  "b, batch-size",
+			Usage: "number of packages or errata to add or remove in one call",
+			Value: app_lifecycle.DefaultBatchSize,
+		},
+	}
+}
+
+type importCmd struct {
+	ctx *cli.Context
+}
+
+// NewImportCmd constructor
+func NewImportCmd(ctx *cli.Context) *importCmd {
+	cmd := new(importCmd)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// Read bundle from the file
+func (cmd *importCmd) Read(filename string) *bundle {
+	source, err := ioutil.ReadFile(filename)
+	utils.Console.CheckError(err)
+
+	data := new(bundle)
+	if bundleFormat(cmd.ctx.String("format"), filename) == "yaml" {
+		err = yaml.Unmarshal(source, data)
+	} else {
+		err = json.Unmarshal(source, data)
+	}
+	utils.Console.CheckError(err)
+	if len(data.Channels) == 0 {
+		utils.Console.ExitOnStderr(fmt.Sprintf("Bundle \"%s\" contains no channels", filename))
+	}
+
+	return data
+}
+
+// Import recreates channels of the bundle or re-merges their content into existing ones
+func (cmd *importCmd) Import(data *bundle) error {
+	lifecycle := app_lifecycle.NewChannelLifecycle(cmd.ctx).SetCurrentConfig()
+	channels, err := lifecycle.GetAllSoftwareChannels()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, channel := range channels {
+		existing[channel.Label] = true
+	}
+
+	utils.Log.Info("Importing %d channels exported from %s at %s", len(data.Channels), data.Server, data.Exported)
+	incomplete := false
+	for _, channel := range data.Channels {
+		if existing[channel.Label] {
+			if cmd.ctx.Bool("clear-channel") {
+				err = lifecycle.ClearChannel(channel.Label)
+			}
+			utils.Log.Info("Merging content into existing channel \"%s\"", channel.Label)
+		} else if channel.Original != "" && existing[channel.Original] {
+			utils.Log.Info("Cloning channel \"%s\" from \"%s\"", channel.Label, channel.Original)
+			err = lifecycle.CloneChannel(channel.Original, channel.Label, &uyuni.ChannelDetails{
+				Label:       channel.Original,
+				Summary:     channel.Summary,
+				ParentLabel: channel.Parent,
+			})
+		} else {
+			utils.Log.Info("Creating channel \"%s\"", channel.Label)
+			err = uyuni.NewClient(utils.RPC).CreateChannel(channel.Label, channel.Name,
+				channel.Summary, channel.Arch, channel.Parent, channel.Checksum)
+			utils.Audit.Record(utils.AuditEntry{Operation: "create", Target: channel.Label}, err)
+		}
+		if err != nil {
+			return err
+		}
+		existing[channel.Label] = true
+
+		missingPackages, err := lifecycle.AddPackages(channel.Label, channel.Packages)
+		if err != nil {
+			return err
+		}
+		for _, ref := range missingPackages {
+			fmt.Printf("Channel \"%s\": package %s is not available on the server\n", channel.Label, ref)
+			incomplete = true
+		}
+
+		advisories := make([]string, len(channel.Errata))
+		for idx, ref := range channel.Errata {
+			advisories[idx] = ref.Advisory
+		}
+		missingErrata, err := lifecycle.AddErrata(channel.Label, advisories)
+		if err != nil {
+			return err
+		}
+		for _, advisory := range missingErrata {
+			fmt.Printf("Channel \"%s\": erratum %s is not available on the server\n", channel.Label, advisory)
+			incomplete = true
+		}
+	}
+
+	if incomplete {
+		return errors.New("Some of the content could not be imported")
+	}
+	fmt.Printf("Imported %d channels\n", len(data.Channels))
+	return nil
+}
+
+// Entry action for the import sub-app
+func MainImportCmd(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Console.ExitOnUnknown("Bundle file required.")
+	}
+	cmd := NewImportCmd(ctx)
+	data := cmd.Read(ctx.Args().First())
+	utils.RefreshCache()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	utils.Console.CheckError(cmd.Import(data))
+
+	return nil
+}
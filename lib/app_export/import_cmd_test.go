@@ -0,0 +1,174 @@
+package app_export
+
+import (
+	"flag"
+	"github.com/isbm/spaceman/lib/app_lifecycle"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// State of the fake server: "sles" base channel with "sles-updates" child, "extra" channel and a package in no channel
+const testState = `{
+  "channels": [
+    {"id": 1, "label": "sles", "name": "sles", "arch_label": "channel-x86_64", "packages": [1, 2, 3], "errata": ["SUSE-1"]},
+    {"id": 2, "label": "sles-updates", "name": "sles-updates", "arch_label": "channel-x86_64", "parent_label": "sles",
+     "packages": [4], "errata": ["SUSE-2"]},
+    {"id": 3, "label": "extra", "name": "extra", "arch_label": "channel-x86_64", "packages": [6], "errata": ["SUSE-3"]}
+  ],
+  "packages": [
+    {"id": 1, "name": "bash", "version": "4.4", "release": "1", "arch_label": "x86_64"},
+    {"id": 2, "name": "curl", "version": "7.66", "release": "1", "arch_label": "x86_64"},
+    {"id": 3, "name": "vim", "version": "8.0", "release": "1", "arch_label": "x86_64"},
+    {"id": 4, "name": "vim", "version": "8.0", "release": "2", "arch_label": "x86_64"},
+    {"id": 5, "name": "zsh", "version": "5.6", "release": "1", "arch_label": "x86_64"},
+    {"id": 6, "name": "openssl", "version": "1.1.1", "release": "1", "arch_label": "x86_64"}
+  ],
+  "errata": [
+    {"id": 1, "advisory": "SUSE-1", "type": "Security Advisory", "issue_date": "2020-01-10", "packages": [3]},
+    {"id": 2, "advisory": "SUSE-2", "type": "Bug Fix Advisory", "issue_date": "2020-02-10", "packages": [4]},
+    {"id": 3, "advisory": "SUSE-3", "type": "Security Advisory", "issue_date": "2020-03-10", "packages": [6]}
+  ],
+  "last_id": 100
+}`
+
+// Create configuration of the fake server with the test state and the audit log in a temporary directory,
+// parse the import options and connect to the server. Returns the import command and the cleanup function.
+func setupImport(t *testing.T, args ...string) (*importCmd, func()) {
+	dir, err := ioutil.TempDir("", "spaceman-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateFile := filepath.Join(dir, "state.json")
+	if err := ioutil.WriteFile(stateFile, []byte(testState), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.conf")
+	content := "server:\n  backend: fake\n  user: admin\n  state_file: " + stateFile + "\n"
+	if err := ioutil.WriteFile(config, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	utils.Audit.SetFile(filepath.Join(dir, "audit.log"))
+
+	app := cli.NewApp()
+	globalSet := flag.NewFlagSet("spaceman", flag.ContinueOnError)
+	globalSet.String("config", "", "")
+	globalSet.String("server", "", "")
+	globalSet.Bool("quiet", true, "")
+	if err := globalSet.Parse([]string{"--config", config}); err != nil {
+		t.Fatal(err)
+	}
+	set := flag.NewFlagSet("import", flag.ContinueOnError)
+	for _, importFlag := range ImportCmdFlags {
+		importFlag.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cli.NewContext(app, set, cli.NewContext(app, globalSet, nil))
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+
+	return NewImportCmd(ctx), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// Get sorted IDs of the channel packages
+func packageIDs(t *testing.T, label string) []int {
+	packages, err := uyuni.NewClient(utils.RPC).ListAllPackages(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, 0, len(packages))
+	for _, pkg := range packages {
+		ids = append(ids, pkg.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Get sorted advisories of the channel errata
+func advisories(t *testing.T, label string) []string {
+	errata, err := uyuni.NewClient(utils.RPC).ListErrata(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(errata))
+	for _, erratum := range errata {
+		names = append(names, erratum.Advisory)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func assertEqual(t *testing.T, what string, expected interface{}, actual interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("%s: expected %v, got %v", what, expected, actual)
+	}
+}
+
+func pkg(name string, version string, release string) app_lifecycle.PackageRef {
+	return app_lifecycle.PackageRef{Name: name, Version: version, Release: release, Arch: "x86_64"}
+}
+
+func TestImportClonesAndCreatesChannels(t *testing.T) {
+	cmd, cleanup := setupImport(t)
+	defer cleanup()
+
+	data := &bundle{Server: "fake://source", Exported: "2020-04-01", Channels: []*channelBundle{
+		{Label: "imp-sles", Name: "imp-sles", Summary: "SLES", Arch: "channel-x86_64", Original: "sles",
+			Packages: []app_lifecycle.PackageRef{pkg("bash", "4.4", "1"), pkg("curl", "7.66", "1"), pkg("vim", "8.0", "1"),
+				pkg("zsh", "5.6", "1")},
+			Errata: []app_lifecycle.ErratumRef{{Advisory: "SUSE-1"}}},
+		{Label: "imp-sles-updates", Name: "imp-sles-updates", Summary: "Updates", Arch: "channel-x86_64", Parent: "imp-sles",
+			Original: "sles-updates", Packages: []app_lifecycle.PackageRef{pkg("vim", "8.0", "2")},
+			Errata: []app_lifecycle.ErratumRef{{Advisory: "SUSE-2"}}},
+		{Label: "imp-tools", Name: "imp-tools", Summary: "Tools", Arch: "channel-x86_64", Parent: "imp-sles",
+			Packages: []app_lifecycle.PackageRef{pkg("openssl", "1.1.1", "1")},
+			Errata:   []app_lifecycle.ErratumRef{{Advisory: "SUSE-3"}}},
+	}}
+	if err := cmd.Import(data); err != nil {
+		t.Fatal(err)
+	}
+
+	client := uyuni.NewClient(utils.RPC)
+	for _, imported := range []struct{ label, original, parent string }{
+		{"imp-sles", "sles", ""},
+		{"imp-sles-updates", "sles-updates", "imp-sles"},
+		{"imp-tools", "", "imp-sles"},
+	} {
+		details, err := client.ChannelDetails(imported.label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, imported.label+" original", imported.original, details.CloneOriginal)
+		assertEqual(t, imported.label+" parent", imported.parent, details.ParentLabel)
+	}
+	assertEqual(t, "imp-sles packages", []int{1, 2, 3, 5}, packageIDs(t, "imp-sles"))
+	assertEqual(t, "imp-sles errata", []string{"SUSE-1"}, advisories(t, "imp-sles"))
+	assertEqual(t, "imp-sles-updates packages", []int{4}, packageIDs(t, "imp-sles-updates"))
+	assertEqual(t, "imp-tools packages", []int{6}, packageIDs(t, "imp-tools"))
+	assertEqual(t, "imp-tools errata", []string{"SUSE-3"}, advisories(t, "imp-tools"))
+}
+
+func TestImportReportsMissingContent(t *testing.T) {
+	cmd, cleanup := setupImport(t)
+	defer cleanup()
+
+	data := &bundle{Channels: []*channelBundle{
+		{Label: "imp-sles", Name: "imp-sles", Summary: "SLES", Arch: "channel-x86_64", Original: "sles",
+			Packages: []app_lifecycle.PackageRef{pkg("nano", "2.9", "1")},
+			Errata:   []app_lifecycle.ErratumRef{{Advisory: "SUSE-9"}}},
+	}}
+	if err := cmd.Import(data); err == nil {
+		t.Error("import of content, which is not on the server, should fail")
+	}
+	assertEqual(t, "imp-sles packages", []int{1, 2, 3}, packageIDs(t, "imp-sles"))
+}
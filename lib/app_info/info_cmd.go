@@ -6,12 +6,11 @@ import (
 	"github.com/isbm/go-asciitable"
 	"github.com/isbm/spaceman/lib/outputters"
 	"github.com/isbm/spaceman/lib/utils"
-	"github.com/thoas/go-funk"
+	"github.com/isbm/spaceman/lib/uyuni"
 	"github.com/urfave/cli"
 	"sort"
 )
 
-var Logger utils.LoggerController
 var InfoCmdFlags []cli.Flag
 
 func init() {
@@ -25,11 +24,67 @@ func init() {
 			Usage:  "list existing channels",
 			Hidden: false,
 		},
+		cli.StringFlag{
+			Name:  "p, package",
+			Usage: "Search package by name and show channels and phases containing it",
+		},
+		cli.BoolFlag{
+			Name:   "a, advanced",
+			Usage:  "treat package search as a Lucene query, e.g. \"name:openssl AND version:1.1*\"",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "e, errata",
+			Usage: "Get information about specified advisory",
+		},
+		cli.BoolFlag{
+			Name:   "E, list-errata",
+			Usage:  "list errata of the channel",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "cve",
+			Usage: "Audit which phases are patched for the CVE and which systems are still affected",
+		},
+		cli.BoolFlag{
+			Name:   "systems",
+			Usage:  "list systems, optionally filtered by --group, --channel, --since and --until of the last check-in",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "system",
+			Usage: "Get information about specified system by ID or name",
+		},
+		cli.StringFlag{
+			Name:  "g, group",
+			Usage: "filter systems by system group",
+		},
+		cli.StringFlag{
+			Name:  "t, type",
+			Usage: "filter errata by type, e.g. \"security\", \"bugfix\" or \"enhancement\"",
+		},
+		cli.StringFlag{
+			Name:  "s, severity",
+			Usage: "filter errata by severity, e.g. \"critical\", \"important\"",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "filter by date (YYYY-MM-DD) starting from",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "filter by date (YYYY-MM-DD) up to",
+		},
+		cli.StringFlag{
+			Name:  "w, workflow",
+			Usage: "use configured workflow to determine phases of the channels",
+		},
 	}
 }
 
 type infoCmd struct {
 	verbose bool
+	api     *uyuni.Client
 	ctx     *cli.Context
 }
 
@@ -37,45 +92,43 @@ type infoCmd struct {
 func NewInfoCmd(ctx *cli.Context) *infoCmd {
 	nfo := new(infoCmd)
 	nfo.ctx = ctx
+	nfo.api = uyuni.NewClient(utils.RPC)
 	return nfo
 }
 
+// Number of details requested from the server in parallel
+const detailsJobs = 8
+
 func (nfo *infoCmd) ChannelDetails(channel string) {
 	if channel == "" {
 		channel = nfo.ctx.String("channel")
 	}
-	out := utils.RPC.RequestFuction("channel.software.getDetails", utils.RPC.GetSession(), channel)
+	details, err := nfo.api.ChannelDetails(channel)
+	utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
 
 	fmt.Printf("\nDetails of channel \"%s\":\n", channel)
-	contentSources := nfo.printMapInfo(out.(map[string]interface{}))
+	nfo.printMapInfo(details.Raw)
 
 	// Content sources
-	for _, udContent := range contentSources {
-		for _, contentSource := range udContent.([]interface{}) {
-			fmt.Printf("\nContent source:\n")
-			nfo.printMapInfo(contentSource.(map[string]interface{}))
-		}
+	for _, source := range details.ContentSources {
+		fmt.Printf("\nContent source:\n")
+		nfo.printMapInfo(source.Raw)
 	}
 
 }
 
 // List available channels tree
 func (nfo *infoCmd) ListAvailableChannels() {
-	Logger.Info("List channels")
-	out := utils.RPC.RequestFuction("channel.listSoftwareChannels", utils.RPC.GetSession())
+	utils.Log.Info("List channels")
+	channels, err := nfo.api.ListSoftwareChannels()
+	utils.Console.CheckError(err)
 	tree := make(map[string][]string)
 
-	for _, dat := range out.([]interface{}) {
-		channel := dat.(map[string]interface{})
-		if channel["parent_label"] != nil {
-			if !funk.Contains(tree, channel["parent_label"]) {
-				tree[channel["parent_label"].(string)] = []string{}
-			}
-			tree[channel["parent_label"].(string)] = append(tree[channel["parent_label"].(string)], channel["label"].(string))
-		} else {
-			if channel["label"] != nil && !funk.Contains(tree, channel["label"]) {
-				tree[channel["label"].(string)] = []string{}
-			}
+	for _, channel := range channels {
+		if !channel.IsBase() {
+			tree[channel.ParentLabel] = append(tree[channel.ParentLabel], channel.Label)
+		} else if _, exist := tree[channel.Label]; !exist && channel.Label != "" {
+			tree[channel.Label] = []string{}
 		}
 	}
 
@@ -143,13 +196,7 @@ func (nfo *infoCmd) printMapInfo(data map[string]interface{}) map[string]interfa
 
 // Set flags from CLI and configuration about current runtime session
 func (nfo *infoCmd) SetCurrentConfig() *infoCmd {
-	if nfo.ctx.GlobalBool("quiet") && nfo.ctx.GlobalBool("verbose") {
-		utils.Console.ExitOnUnknown("Don't know how to be quietly verbose.")
-	}
-
-	Logger = *utils.NewLoggerController(nfo.ctx.GlobalBool("verbose"), nfo.ctx.GlobalBool("verbose"),
-		!nfo.ctx.GlobalBool("quiet"), nfo.ctx.GlobalBool("verbose"))
-	Logger.Debug("Configuration set")
+	utils.Log.Debug("Configuration set")
 
 	return nfo
 }
@@ -157,11 +204,25 @@ func (nfo *infoCmd) SetCurrentConfig() *infoCmd {
 // Entry action for the info sub-app
 func MainInfoCmd(ctx *cli.Context) error {
 	nfo := NewInfoCmd(ctx).SetCurrentConfig()
-	utils.RPC.Connect((*utils.Configuration.GetConfig(ctx, "server")))
-	if ctx.String("channel") != "" {
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	if ctx.Bool("systems") {
+		nfo.ListSystems()
+	} else if ctx.String("system") != "" {
+		nfo.SystemDetails(ctx.String("system"))
+	} else if ctx.String("channel") != "" && ctx.Bool("list-errata") {
+		nfo.ListChannelErrata(ctx.String("channel"))
+	} else if ctx.String("channel") != "" {
 		nfo.ChannelDetails("")
 	} else if ctx.Bool("list-channels") {
 		nfo.ListAvailableChannels()
+	} else if ctx.String("package") != "" {
+		nfo.SearchPackages(ctx.String("package"))
+	} else if ctx.String("errata") != "" {
+		nfo.ErratumDetails(ctx.String("errata"))
+	} else if ctx.String("cve") != "" {
+		nfo.AuditCve(ctx.String("cve"))
+	} else if ctx.Bool("list-errata") {
+		utils.Console.ExitOnUnknown("Channel required.")
 	} else {
 		utils.Console.ExitOnUnknown("Don't know what kind of info you would like to have.")
 	}
@@ -0,0 +1,184 @@
+package app_info
+
+import (
+	"fmt"
+	"github.com/aybabtme/rgbterm"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/thoas/go-funk"
+	"sort"
+	"strings"
+)
+
+// Get advisories fixing the CVE and channels where each of them is present
+func (nfo *infoCmd) cveAdvisories(cve string) ([]string, map[string][]string) {
+	advisories := make([]string, 0)
+	fixedIn := make(map[string][]string)
+	errata, err := nfo.api.FindErrataByCVE(cve)
+	utils.Console.CheckError(err)
+	for _, erratum := range errata {
+		advisory := erratum.Advisory
+		if advisory == "" || funk.ContainsString(advisories, advisory) {
+			continue
+		}
+		advisories = append(advisories, advisory)
+		channels, err := nfo.api.ErratumChannels(advisory)
+		utils.Console.CheckError(err)
+		for _, channel := range channels {
+			label := channel.Label
+			fixedIn[label] = append(fixedIn[label], advisory)
+		}
+	}
+	sort.Strings(advisories)
+
+	return advisories, fixedIn
+}
+
+// Get channel trees: root channel label with labels of all its channels, including the root
+func (nfo *infoCmd) channelTrees() map[string][]string {
+	channels, err := nfo.api.ListSoftwareChannels()
+	utils.Console.CheckError(err)
+	trees := make(map[string][]string)
+	for _, channel := range channels {
+		root := channel.ParentLabel
+		if channel.IsBase() {
+			root = channel.Label
+		}
+		trees[root] = append(trees[root], channel.Label)
+	}
+	return trees
+}
+
+// Show per channel tree and per phase, whether advisories fixing the CVE are present
+func (nfo *infoCmd) auditCvePhases(cve string, affectedChannels []string) {
+	workflow, err := utils.NewWorkflow(nfo.ctx, nfo.ctx.String("workflow"))
+	utils.Console.CheckError(err)
+
+	advisories, fixedIn := nfo.cveAdvisories(cve)
+	if len(advisories) == 0 {
+		fmt.Printf("\nNo errata found fixing %s\n\n", cve)
+		return
+	}
+	fmt.Printf("\nErrata fixing %s: %s\n", cve, strings.Join(advisories, ", "))
+
+	// Group trees by the label without phase, so the same tree in all phases goes together
+	type treePhase struct {
+		name  string
+		phase string
+		fixes []string
+	}
+	relevant := make(map[string]bool)
+	phased := make([]treePhase, 0)
+	for root, labels := range nfo.channelTrees() {
+		entry := treePhase{name: root, phase: workflow.PhaseOf(root), fixes: make([]string, 0)}
+		if entry.phase != "" {
+			entry.name = root[len(entry.phase)+len(workflow.Delimiter()):]
+		}
+		for _, label := range labels {
+			for _, advisory := range fixedIn[label] {
+				if !funk.ContainsString(entry.fixes, advisory) {
+					entry.fixes = append(entry.fixes, advisory)
+				}
+			}
+			if funk.ContainsString(affectedChannels, label) {
+				relevant[entry.name] = true
+			}
+		}
+		if len(entry.fixes) > 0 {
+			relevant[entry.name] = true
+		}
+		sort.Strings(entry.fixes)
+		phased = append(phased, entry)
+	}
+
+	phaseIndex := make(map[string]int)
+	for idx, phase := range workflow.Phases() {
+		phaseIndex[phase] = idx + 1
+	}
+	sort.Slice(phased, func(i, j int) bool {
+		if phased[i].name != phased[j].name {
+			return phased[i].name < phased[j].name
+		}
+		return phaseIndex[phased[i].phase] < phaseIndex[phased[j].phase]
+	})
+
+	rows := make([][]interface{}, 0)
+	for _, entry := range phased {
+		if !relevant[entry.name] {
+			continue
+		}
+		var phase, fixes interface{}
+		if entry.phase != "" {
+			phase = entry.phase
+		}
+		status := rgbterm.FgString("not patched", 0xff, 0x40, 0x40)
+		if len(entry.fixes) > 0 {
+			status = rgbterm.FgString("patched", 0x40, 0xff, 0x40)
+			fixes = entry.fixes
+		}
+		rows = append(rows, []interface{}{entry.name, phase, status, fixes})
+	}
+	fmt.Printf("\nPatch status of channel trees (workflow \"%s\"):\n", workflow.Name())
+	outputters.NewAnsiCLI().Table([]string{"Channel tree", "Phase", "Status", "Errata"}, rows)
+}
+
+// Audit which channel trees and phases are patched for the CVE and which systems are still affected
+func (nfo *infoCmd) AuditCve(cve string) {
+	cve = strings.ToUpper(cve)
+	utils.Log.Info("Auditing %s", cve)
+
+	statuses, err := nfo.api.CVEPatchStatus(cve)
+	utils.Console.CheckFault(err, cve)
+	affected := make([]uyuni.PatchStatus, 0)
+	for _, status := range statuses {
+		if status.IsAffected() {
+			affected = append(affected, status)
+		}
+	}
+
+	names := make(map[int]string)
+	if len(affected) > 0 {
+		all, err := nfo.api.ListSystems()
+		utils.Console.CheckError(err)
+		for _, system := range all {
+			names[system.ID] = system.Name
+		}
+	}
+
+	// Channels of the patch status are those with the fix, affected channels are the ones the systems are subscribed to
+	subscribed := make([][]string, len(affected))
+	utils.Console.CheckError(utils.Parallel(len(affected), detailsJobs, func(idx int) error {
+		base, children, err := nfo.api.SubscribedChannels(affected[idx].SystemID)
+		if base != "" {
+			subscribed[idx] = append([]string{base}, children...)
+		} else {
+			subscribed[idx] = children
+		}
+		return err
+	}, nil))
+
+	affectedChannels := make([]string, 0)
+	rows := make([][]interface{}, 0)
+	for idx, system := range affected {
+		affectedChannels = append(affectedChannels, subscribed[idx]...)
+		status := strings.ToLower(strings.Replace(system.Status, "_", " ", -1))
+		var advisories interface{}
+		if len(system.Advisories) > 0 {
+			advisories = system.Advisories
+		}
+		rows = append(rows, []interface{}{names[system.SystemID], system.SystemID, status, subscribed[idx], advisories})
+	}
+
+	nfo.auditCvePhases(cve, affectedChannels)
+
+	if len(rows) == 0 {
+		fmt.Printf("No systems are affected by %s\n\n", cve)
+		return
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0].(string) < rows[j][0].(string)
+	})
+	fmt.Printf("Systems affected by %s:\n", cve)
+	outputters.NewAnsiCLI().Table([]string{"System", "ID", "Status", "Channels", "Errata"}, rows)
+}
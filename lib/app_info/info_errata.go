@@ -0,0 +1,154 @@
+package app_info
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Show details of the erratum, its CVEs and packages
+func (nfo *infoCmd) ErratumDetails(advisory string) {
+	details, err := nfo.api.ErratumDetails(advisory)
+	utils.Console.CheckFault(err, fmt.Sprintf("Erratum \"%s\"", advisory))
+	fmt.Printf("\nDetails of erratum \"%s\":\n", advisory)
+	nfo.printMapInfo(details.Raw)
+
+	cves, err := nfo.api.ErratumCVEs(advisory)
+	utils.Console.CheckError(err)
+	sort.Strings(cves)
+	if len(cves) > 0 {
+		fmt.Printf("CVEs: %s\n\n", strings.Join(cves, ", "))
+	}
+
+	packages, err := nfo.api.ErratumPackages(advisory)
+	utils.Console.CheckError(err)
+	rows := make([][]interface{}, 0)
+	for _, pkg := range packages {
+		var channels interface{}
+		if len(pkg.Channels) > 0 {
+			channels = pkg.Channels
+		}
+		rows = append(rows, []interface{}{pkg.String(), pkg.Arch, channels})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0].(string) < rows[j][0].(string)
+	})
+	if len(rows) > 0 {
+		fmt.Println("Packages:")
+		outputters.NewAnsiCLI().Table([]string{"Package", "Arch", "Channels"}, rows)
+	}
+}
+
+// Parse date filter option in YYYY-MM-DD format
+func (nfo *infoCmd) dateOption(name string) time.Time {
+	var date time.Time
+	if value := nfo.ctx.String(name); value != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.Console.ExitOnUnknown(fmt.Sprintf("Date \"%s\" should be in YYYY-MM-DD format.", value))
+		}
+	}
+	return date
+}
+
+// Tell if erratum type matches the filter. Filter is a case-insensitive part of the type, e.g. "security" or "bugfix".
+func (nfo *infoCmd) matchesType(advisoryType string, filter string) bool {
+	normalise := func(text string) string {
+		return strings.ToLower(strings.Replace(text, " ", "", -1))
+	}
+	return filter == "" || strings.Contains(normalise(advisoryType), normalise(filter))
+}
+
+// Get severity of the erratum from its details. Older servers have no severity field,
+// in which case it is taken from the synopsis, e.g. "Important: openssl security update".
+func (nfo *infoCmd) erratumSeverity(advisory string) (string, error) {
+	details, err := nfo.api.ErratumDetails(advisory)
+	if err != nil {
+		return "", err
+	}
+	if details.Severity != "" {
+		return details.Severity, nil
+	}
+	synopsis := details.Synopsis
+	if idx := strings.Index(synopsis, ":"); idx > 0 {
+		return strings.TrimSpace(synopsis[:idx]), nil
+	}
+	return "", nil
+}
+
+// Get severities of the errata. Severity from the list is used, if the server provides it,
+// otherwise details of the errata are requested in parallel.
+func (nfo *infoCmd) errataSeverities(errata []uyuni.Erratum) ([]string, error) {
+	severities := make([]string, len(errata))
+	return severities, utils.Parallel(len(errata), detailsJobs, func(idx int) error {
+		if errata[idx].Severity != "" {
+			severities[idx] = errata[idx].Severity
+			return nil
+		}
+		var err error
+		severities[idx], err = nfo.erratumSeverity(errata[idx].Advisory)
+		return err
+	}, nil)
+}
+
+// List errata of the channel, filtered by type, severity and issue date
+func (nfo *infoCmd) ListChannelErrata(channel string) {
+	var out []uyuni.Erratum
+	var err error
+	since, until := nfo.dateOption("since"), nfo.dateOption("until")
+	if since.IsZero() && until.IsZero() {
+		out, err = nfo.api.ListErrata(channel)
+	} else {
+		if until.IsZero() {
+			until = time.Now()
+		} else {
+			until = until.Add(24*time.Hour - time.Second)
+		}
+		out, err = nfo.api.ListErrataBetween(channel, since, until)
+	}
+	utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
+
+	typeFilter, severityFilter := nfo.ctx.String("type"), nfo.ctx.String("severity")
+	matching := make([]uyuni.Erratum, 0)
+	for _, erratum := range out {
+		if nfo.matchesType(erratum.Type, typeFilter) {
+			matching = append(matching, erratum)
+		}
+	}
+	var severities []string
+	if severityFilter != "" {
+		severities, err = nfo.errataSeverities(matching)
+		utils.Console.CheckError(err)
+	}
+
+	rows := make([][]interface{}, 0)
+	for idx, erratum := range matching {
+		row := []interface{}{erratum.Advisory, erratum.Type, erratum.Synopsis, erratum.Issued}
+		if severityFilter != "" {
+			if !strings.EqualFold(severities[idx], severityFilter) {
+				continue
+			}
+			row = append(row, severities[idx])
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		utils.Console.ExitOnStderr(fmt.Sprintf("No errata found in channel \"%s\"", channel))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0].(string) < rows[j][0].(string)
+	})
+
+	header := []string{"Advisory", "Type", "Synopsis", "Issued"}
+	if severityFilter != "" {
+		header = append(header, "Severity")
+	}
+	fmt.Printf("\nErrata of channel \"%s\":\n", channel)
+	outputters.NewAnsiCLI().Table(header, rows)
+}
@@ -0,0 +1,75 @@
+package app_info
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"sort"
+)
+
+// Search packages by name (or Lucene query) and show which channels and phases contain them
+func (nfo *infoCmd) SearchPackages(query string) {
+	utils.Log.Info("Searching packages for \"%s\"", query)
+	packages, err := nfo.api.SearchPackages(query, nfo.ctx.Bool("advanced"))
+	utils.Console.CheckError(err)
+	if len(packages) == 0 {
+		utils.Console.ExitOnStderr(fmt.Sprintf("No packages found for \"%s\"", query))
+	}
+
+	workflow, err := utils.NewWorkflow(nfo.ctx, nfo.ctx.String("workflow"))
+	utils.Console.CheckError(err)
+	phaseIndex := make(map[string]int)
+	for idx, phase := range workflow.Phases() {
+		phaseIndex[phase] = idx + 1
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].String() < packages[j].String()
+	})
+
+	rows := make([][]interface{}, 0)
+	for _, pkg := range packages {
+		nevra := pkg.String()
+		utils.Log.Debug("Looking up channels and errata for %s", nevra)
+
+		providingErrata, err := nfo.api.PackageErrata(pkg.ID)
+		utils.Console.CheckError(err)
+		advisories := make([]string, 0)
+		for _, erratum := range providingErrata {
+			advisories = append(advisories, erratum.Advisory)
+		}
+		sort.Strings(advisories)
+		var errata interface{}
+		if len(advisories) > 0 {
+			errata = advisories
+		}
+
+		providingChannels, err := nfo.api.PackageChannels(pkg.ID)
+		utils.Console.CheckError(err)
+		labels := make([]string, 0)
+		for _, channel := range providingChannels {
+			labels = append(labels, channel.Label)
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			pi, pj := phaseIndex[workflow.PhaseOf(labels[i])], phaseIndex[workflow.PhaseOf(labels[j])]
+			if pi != pj {
+				return pi < pj
+			}
+			return labels[i] < labels[j]
+		})
+
+		if len(labels) == 0 {
+			rows = append(rows, []interface{}{nevra, pkg.Arch, nil, nil, errata})
+		}
+		for _, label := range labels {
+			var phase interface{}
+			if name := workflow.PhaseOf(label); name != "" {
+				phase = name
+			}
+			rows = append(rows, []interface{}{nevra, pkg.Arch, label, phase, errata})
+		}
+	}
+
+	fmt.Printf("\nPackages matching \"%s\" (workflow \"%s\"):\n", query, workflow.Name())
+	outputters.NewAnsiCLI().Table([]string{"Package", "Arch", "Channel", "Phase", "Errata"}, rows)
+}
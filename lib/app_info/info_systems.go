@@ -0,0 +1,153 @@
+package app_info
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Get string value of the key from the API response map or an empty string
+func (nfo *infoCmd) value(data map[string]interface{}, key string) string {
+	value, exist := data[key]
+	if !exist || value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", value))
+}
+
+// ResolveSystem returns system ID by its ID or profile name
+func ResolveSystem(idOrName string) (int, error) {
+	if sid, err := strconv.Atoi(idOrName); err == nil {
+		return sid, nil
+	}
+
+	found, err := utils.AsStructs(utils.RPC.RequestFuction("system.getId", idOrName))
+	if err != nil {
+		return 0, err
+	}
+	switch len(found) {
+	case 0:
+		return 0, fmt.Errorf("System \"%s\" was not found", idOrName)
+	case 1:
+		sid, ok := found[0]["id"].(int)
+		if !ok {
+			return 0, fmt.Errorf("Unable to get ID of the system \"%s\"", idOrName)
+		}
+		return sid, nil
+	default:
+		return 0, fmt.Errorf("There are %d systems named \"%s\", please use system ID instead", len(found), idOrName)
+	}
+}
+
+// Count elements of the list, returned by the API call
+func (nfo *infoCmd) countOf(method string, sid int) int {
+	list, err := utils.AsList(utils.RPC.RequestFuction(method, sid))
+	utils.Console.CheckError(err)
+	return len(list)
+}
+
+// Show system details: channel subscriptions, pending updates, installed packages and entitlements
+func (nfo *infoCmd) SystemDetails(idOrName string) {
+	sid, err := ResolveSystem(idOrName)
+	utils.Console.CheckError(err)
+
+	details, err := utils.AsStruct(utils.RPC.RequestFuction("system.getDetails", sid))
+	utils.Console.CheckFault(err, fmt.Sprintf("System \"%s\"", idOrName))
+	fmt.Printf("\nDetails of system \"%s\":\n", idOrName)
+	nfo.printMapInfo(details)
+
+	summary := make(map[string]interface{})
+	base, err := utils.AsStruct(utils.RPC.RequestFuction("system.getSubscribedBaseChannel", sid))
+	if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+		base, err = map[string]interface{}{}, nil
+	}
+	utils.Console.CheckError(err)
+	if label := nfo.value(base, "label"); label != "" {
+		summary["base_channel"] = label
+	} else {
+		summary["base_channel"] = nil
+	}
+
+	childChannels, err := utils.AsStructs(utils.RPC.RequestFuction("system.listSubscribedChildChannels", sid))
+	utils.Console.CheckError(err)
+	children := make([]string, 0)
+	for _, channel := range childChannels {
+		children = append(children, nfo.value(channel, "label"))
+	}
+	sort.Strings(children)
+	summary["child_channels"] = nil
+	if len(children) > 0 {
+		summary["child_channels"] = strings.Join(children, ", ")
+	}
+
+	entitlementList, err := utils.AsList(utils.RPC.RequestFuction("system.getEntitlements", sid))
+	utils.Console.CheckError(err)
+	entitlements := make([]string, 0)
+	for _, entitlement := range entitlementList {
+		entitlements = append(entitlements, fmt.Sprintf("%v", entitlement))
+	}
+	summary["entitlements"] = strings.Join(entitlements, ", ")
+
+	summary["pending_errata"] = nfo.countOf("system.getRelevantErrata", sid)
+	summary["upgradable_packages"] = nfo.countOf("system.listLatestUpgradablePackages", sid)
+	summary["installed_packages"] = nfo.countOf("system.listPackages", sid)
+
+	fmt.Println("Subscriptions and updates:")
+	nfo.printMapInfo(summary)
+}
+
+// List systems, filtered by group, subscribed channel and last check-in date
+func (nfo *infoCmd) ListSystems() {
+	var out []map[string]interface{}
+	var err error
+	if group := nfo.ctx.String("group"); group != "" {
+		out, err = utils.AsStructs(utils.RPC.RequestFuction("systemgroup.listSystemsMinimal", group))
+		utils.Console.CheckFault(err, fmt.Sprintf("System group \"%s\"", group))
+	} else {
+		out, err = utils.AsStructs(utils.RPC.RequestFuction("system.listSystems"))
+		utils.Console.CheckError(err)
+	}
+
+	var subscribed map[string]bool
+	if channel := nfo.ctx.String("channel"); channel != "" {
+		systems, err := nfo.api.ListSubscribedSystems(channel)
+		utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
+		subscribed = make(map[string]bool)
+		for _, system := range systems {
+			subscribed[fmt.Sprint(system.ID)] = true
+		}
+	}
+
+	since, until := nfo.dateOption("since"), nfo.dateOption("until")
+	if !until.IsZero() {
+		until = until.Add(24 * time.Hour)
+	}
+
+	rows := make([][]interface{}, 0)
+	for _, system := range out {
+		if subscribed != nil && !subscribed[nfo.value(system, "id")] {
+			continue
+		}
+		checkin, ok := system["last_checkin"].(time.Time)
+		if (!since.IsZero() || !until.IsZero()) && !ok {
+			continue
+		}
+		if (!since.IsZero() && checkin.Before(since)) || (!until.IsZero() && !checkin.Before(until)) {
+			continue
+		}
+		rows = append(rows, []interface{}{system["id"], nfo.value(system, "name"), system["last_checkin"]})
+	}
+
+	if len(rows) == 0 {
+		utils.Console.ExitOnStderr("No systems has been found")
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][1].(string) < rows[j][1].(string)
+	})
+	fmt.Println()
+	outputters.NewAnsiCLI().Table([]string{"ID", "Name", "Last check-in"}, rows)
+}
@@ -0,0 +1,72 @@
+package app_lifecycle
+
+import (
+	"github.com/isbm/spaceman/lib/outputters"
+)
+
+// Default number of packages or errata sent in one call
+const DefaultBatchSize = 500
+
+// Get number of items to send in one call
+func (lifecycle *channelLifecycle) batchSize() int {
+	if size := lifecycle.ctx.Int("batch-size"); size > 0 {
+		return size
+	}
+	return DefaultBatchSize
+}
+
+// Get progress bar of the bulk operation. Bars of parallel workers would overwrite each other, so they have none.
+func (lifecycle *channelLifecycle) progress(title string, total int) *outputters.Progress {
+	if lifecycle.ctx.Int("jobs") > 1 || lifecycle.ctx.GlobalBool("quiet") {
+		return nil
+	}
+	return outputters.NewProgress(title, total)
+}
+
+// Call the operation for every batch of items from start to end index, showing the progress
+func (lifecycle *channelLifecycle) inBatches(title string, total int, operation func(start int, end int) error) error {
+	size := lifecycle.batchSize()
+	progress := lifecycle.progress(title, total)
+	defer progress.Finish()
+
+	for start := 0; start < total; start += size {
+		end := start + size
+		if end > total {
+			end = total
+		}
+		if err := operation(start, end); err != nil {
+			return err
+		}
+		progress.Add(end - start)
+	}
+	return nil
+}
+
+// Remove errata from the channel in batches
+func (lifecycle *channelLifecycle) removeErrata(label string, advisories []string) error {
+	return lifecycle.inBatches("Removing errata", len(advisories), func(start int, end int) error {
+		return lifecycle.api.RemoveErrata(label, advisories[start:end], false)
+	})
+}
+
+// Remove packages from the channel in batches
+func (lifecycle *channelLifecycle) removePackages(label string, ids []int) error {
+	return lifecycle.inBatches("Removing packages", len(ids), func(start int, end int) error {
+		return lifecycle.api.RemovePackages(label, ids[start:end])
+	})
+}
+
+// Add packages to the channel in batches
+func (lifecycle *channelLifecycle) addPackages(label string, ids []int) error {
+	return lifecycle.inBatches("Adding packages", len(ids), func(start int, end int) error {
+		return lifecycle.api.AddPackages(label, ids[start:end])
+	})
+}
+
+// Merge errata from another channel in batches
+func (lifecycle *channelLifecycle) mergeErrata(source string, label string, advisories []string) error {
+	return lifecycle.inBatches("Merging errata", len(advisories), func(start int, end int) error {
+		_, err := lifecycle.api.MergeErrata(source, label, advisories[start:end]...)
+		return err
+	})
+}
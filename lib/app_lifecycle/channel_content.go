@@ -0,0 +1,197 @@
+package app_lifecycle
+
+import (
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/thoas/go-funk"
+	"sort"
+)
+
+// PackageRef identifies a package by NEVRA and checksum, independently from the server
+type PackageRef struct {
+	Name         string `json:"name" yaml:"name"`
+	Epoch        string `json:"epoch,omitempty" yaml:"epoch,omitempty"`
+	Version      string `json:"version" yaml:"version"`
+	Release      string `json:"release" yaml:"release"`
+	Arch         string `json:"arch" yaml:"arch"`
+	Checksum     string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	ChecksumType string `json:"checksum_type,omitempty" yaml:"checksum_type,omitempty"`
+}
+
+// ErratumRef identifies an erratum by its advisory name
+type ErratumRef struct {
+	Advisory string `json:"advisory" yaml:"advisory"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Synopsis string `json:"synopsis,omitempty" yaml:"synopsis,omitempty"`
+	Issued   string `json:"issued,omitempty" yaml:"issued,omitempty"`
+}
+
+// Format package as NEVRA
+func (ref PackageRef) String() string {
+	return uyuni.Package{Name: ref.Name, Epoch: ref.Epoch, Version: ref.Version, Release: ref.Release, Arch: ref.Arch}.String()
+}
+
+// ChannelPackages returns references of all packages in the channel
+func (lifecycle *channelLifecycle) ChannelPackages(label string) ([]PackageRef, error) {
+	pkgs, err := lifecycle.api.ListAllPackages(label)
+	if err != nil {
+		return nil, lifecycle.channelError(label, err)
+	}
+	packages := make([]PackageRef, 0)
+	for _, pkg := range pkgs {
+		packages = append(packages, PackageRef{
+			Name:         pkg.Name,
+			Epoch:        pkg.Epoch,
+			Version:      pkg.Version,
+			Release:      pkg.Release,
+			Arch:         pkg.Arch,
+			Checksum:     pkg.Checksum,
+			ChecksumType: pkg.ChecksumType,
+		})
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].String() < packages[j].String()
+	})
+
+	return packages, nil
+}
+
+// ChannelErrata returns references of all errata in the channel
+func (lifecycle *channelLifecycle) ChannelErrata(label string) ([]ErratumRef, error) {
+	errataData, err := lifecycle.api.ListErrata(label)
+	if err != nil {
+		return nil, lifecycle.channelError(label, err)
+	}
+	errata := make([]ErratumRef, 0)
+	for _, erratum := range errataData {
+		errata = append(errata, ErratumRef{
+			Advisory: erratum.Advisory,
+			Type:     erratum.Type,
+			Synopsis: erratum.Synopsis,
+			Issued:   erratum.Issued,
+		})
+	}
+	sort.Slice(errata, func(i, j int) bool {
+		return errata[i].Advisory < errata[j].Advisory
+	})
+
+	return errata, nil
+}
+
+// Find ID of the package on the server by its NEVRA and checksum. Returns 0 if there is no such package.
+func (lifecycle *channelLifecycle) findPackage(ref PackageRef) (int, error) {
+	candidates, err := lifecycle.api.FindPackages(ref.Name, ref.Version, ref.Release, ref.Epoch, ref.Arch)
+	if err != nil {
+		return 0, err
+	}
+	for _, candidate := range candidates {
+		if candidate.ID == 0 {
+			continue
+		}
+		if ref.Checksum == "" {
+			return candidate.ID, nil
+		}
+		details, err := lifecycle.api.PackageDetails(candidate.ID)
+		if err != nil {
+			return 0, err
+		}
+		if details.Checksum == ref.Checksum {
+			return candidate.ID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// AddPackages adds packages, which are missing in the channel, by their NEVRA and checksum.
+// Returns packages that are not present on the server at all.
+func (lifecycle *channelLifecycle) AddPackages(label string, packages []PackageRef) ([]PackageRef, error) {
+	channelPackages, err := lifecycle.ChannelPackages(label)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool)
+	for _, ref := range channelPackages {
+		present[ref.String()+ref.Checksum] = true
+	}
+
+	ids := make([]int, 0)
+	missing := make([]PackageRef, 0)
+	for _, ref := range packages {
+		if present[ref.String()+ref.Checksum] {
+			continue
+		}
+		pid, err := lifecycle.findPackage(ref)
+		if err != nil {
+			return nil, err
+		}
+		if pid != 0 {
+			ids = append(ids, pid)
+		} else {
+			missing = append(missing, ref)
+		}
+	}
+
+	if len(ids) > 0 {
+		lifecycle.logger.Info("Adding %d packages to channel \"%s\"", len(ids), label)
+		err := lifecycle.addPackages(label, ids)
+		entry := utils.AuditEntry{Operation: "add", Target: label, Counts: map[string]int{"packages": len(ids)}}
+		if err := lifecycle.audit(entry, err); err != nil {
+			return nil, lifecycle.channelError(label, err)
+		}
+	}
+
+	return missing, nil
+}
+
+// AddErrata merges errata, which are missing in the channel, from any other channel that provides them.
+// Returns advisories that are not present on the server at all.
+func (lifecycle *channelLifecycle) AddErrata(label string, advisories []string) ([]string, error) {
+	channelErrata, err := lifecycle.ChannelErrata(label)
+	if err != nil {
+		return nil, err
+	}
+	present := make([]string, 0)
+	for _, ref := range channelErrata {
+		present = append(present, ref.Advisory)
+	}
+
+	// Group advisories by a channel to merge them from
+	sources := make(map[string][]string)
+	missing := make([]string, 0)
+	for _, advisory := range advisories {
+		if funk.ContainsString(present, advisory) {
+			continue
+		}
+		channels, err := lifecycle.api.ErratumChannels(advisory)
+		if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+			channels, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		source := ""
+		for _, channel := range channels {
+			if channel.Label != label {
+				source = channel.Label
+				break
+			}
+		}
+		if source == "" {
+			missing = append(missing, advisory)
+		} else {
+			sources[source] = append(sources[source], advisory)
+		}
+	}
+
+	for source, names := range sources {
+		lifecycle.logger.Info("Merging %d errata from channel \"%s\" to channel \"%s\"", len(names), source, label)
+		err := lifecycle.mergeErrata(source, label, names)
+		entry := utils.AuditEntry{Operation: "merge", Source: source, Target: label, Counts: map[string]int{"errata": len(names)}}
+		if err := lifecycle.audit(entry, err); err != nil {
+			return nil, lifecycle.channelError(label, err)
+		}
+	}
+
+	return missing, nil
+}
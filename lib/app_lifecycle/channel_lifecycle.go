@@ -1,18 +1,19 @@
 package app_lifecycle
 
 import (
+	"errors"
 	"fmt"
 	"github.com/aybabtme/rgbterm"
 	"github.com/isbm/spaceman/lib/app_info"
 	"github.com/isbm/spaceman/lib/utils"
-	"github.com/thoas/go-funk"
+	"github.com/isbm/spaceman/lib/uyuni"
 	"github.com/urfave/cli"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
-var Logger utils.LoggerController
 var ChannelLifecycleFlags []cli.Flag
 
 func init() {
@@ -94,6 +95,20 @@ func init() {
 			Usage:  "list configured workflows",
 			Hidden: false,
 		},
+		cli.StringFlag{
+			Name:  "to-server",
+			Usage: "promote channel to another configured server",
+		},
+		cli.IntFlag{
+			Name:  "j, jobs",
+			Usage: "number of child channels to process in parallel",
+			Value: 1,
+		},
+		cli.IntFlag{
+			Name:  "b, batch-size",
+			Usage: "number of packages or errata to add or remove in one call",
+			Value: DefaultBatchSize,
+		},
 	}
 }
 
@@ -101,8 +116,13 @@ type channelLifecycle struct {
 	phases                    []string
 	excludedChannels          []string
 	filterChannels            []string
-	allSoftwareChannelsCached []interface{}
+	allSoftwareChannelsCached []uyuni.Channel
 	phasesDelimiter           string
+	workflow                  *utils.Workflow
+	api                       *uyuni.Client
+	logger                    *utils.LoggerController
+	server                    string
+	user                      string
 	ctx                       *cli.Context
 }
 
@@ -112,51 +132,108 @@ func NewChannelLifecycle(context *cli.Context) *channelLifecycle {
 	lifecycle.ctx = context
 	lifecycle.phasesDelimiter = "-"
 	lifecycle.allSoftwareChannelsCached = nil
+	lifecycle.api = uyuni.NewClient(utils.RPC)
+	lifecycle.logger = utils.Log
 
 	return lifecycle
 }
 
-// Promote channel to the specific stage
-func (lifecycle *channelLifecycle) promoteChannel(channelName string, init bool) string {
+// Get a copy of the lifecycle, which processes channels on another server. Server and user are recorded in the audit log.
+func (lifecycle *channelLifecycle) onServer(client uyuni.Requester, server string, user string) *channelLifecycle {
+	remote := *lifecycle
+	remote.api = uyuni.NewClient(client)
+	remote.allSoftwareChannelsCached = nil
+	remote.server, remote.user = server, user
+
+	return &remote
+}
+
+// Record the operation in the audit log and pass its error through
+func (lifecycle *channelLifecycle) audit(entry utils.AuditEntry, err error) error {
+	if lifecycle.workflow != nil {
+		entry.Workflow = lifecycle.workflow.Name()
+	}
+	entry.Server, entry.User = lifecycle.server, lifecycle.user
+	utils.Audit.Record(entry, err)
+
+	return err
+}
+
+// Get label of the channel in the next phase. Errors are returned, so concurrent workers report them together.
+func (lifecycle *channelLifecycle) promoteChannel(channelName string, init bool) (string, error) {
 	currentPhase := lifecycle.extractPhaseName(channelName)
 	if currentPhase == "" && !init {
-		utils.Console.ExitOnStderr("Unable to get phase")
+		return "", fmt.Errorf("Unable to get phase of channel \"%s\"", channelName)
+	}
+	nextPhase, err := lifecycle.getNextPhase(currentPhase, init)
+	if err != nil {
+		return "", err
 	}
-	nextPhase := lifecycle.getNextPhase(currentPhase, init)
 
 	if nextPhase != "" && !init {
 		channelName = fmt.Sprintf("%s%s%s", nextPhase, lifecycle.phasesDelimiter, channelName[len(currentPhase)+1:])
 	} else if nextPhase != "" && currentPhase != "" && init {
-		utils.Console.ExitOnStderr("Channel is already initalised. Please just promote it.")
+		return "", fmt.Errorf("Channel \"%s\" is already initalised. Please just promote it.", channelName)
 	} else if nextPhase != "" && currentPhase == "" && init {
 		channelName = fmt.Sprintf("%s%s%s", nextPhase, lifecycle.phasesDelimiter, channelName)
 	} else {
-		utils.Console.ExitOnStderr("Unable to promote channel.")
+		return "", fmt.Errorf("Unable to promote channel \"%s\"", channelName)
 	}
 
-	return channelName
+	return channelName, nil
+}
+
+// Get label of the destination channel: the archive, the unarchived channel or the channel in the next phase
+func (lifecycle *channelLifecycle) destinationLabel(labelSrc string) (string, error) {
+	if lifecycle.ctx.Bool("archive") {
+		return lifecycle.MakeArchiveLabel(labelSrc)
+	} else if lifecycle.ctx.Bool("rollback") {
+		return lifecycle.UnarchiveLabel(labelSrc)
+	}
+	return lifecycle.promoteChannel(labelSrc, lifecycle.ctx.Bool("init"))
+}
+
+// Get destination of the channel parent, so the promoted child channel belongs to it. Base channel has none.
+func (lifecycle *channelLifecycle) parentDestination(label string) (string, error) {
+	channels, err := lifecycle.GetAllSoftwareChannels()
+	if err != nil {
+		return "", err
+	}
+	for _, channel := range channels {
+		if channel.Label == label && channel.ParentLabel != "" {
+			return lifecycle.destinationLabel(channel.ParentLabel)
+		}
+	}
+	return "", nil
 }
 
 // Get all software channels
-func (lifecycle *channelLifecycle) GetAllSoftwareChannels() []interface{} {
+func (lifecycle *channelLifecycle) GetAllSoftwareChannels() ([]uyuni.Channel, error) {
 	if lifecycle.allSoftwareChannelsCached == nil {
-		lifecycle.allSoftwareChannelsCached = utils.RPC.RequestFuction("channel.listSoftwareChannels", utils.RPC.GetSession()).([]interface{})
+		channels, err := lifecycle.api.ListSoftwareChannels()
+		if err != nil {
+			return nil, err
+		}
+		lifecycle.allSoftwareChannelsCached = channels
 	}
 
-	return lifecycle.allSoftwareChannelsCached
+	return lifecycle.allSoftwareChannelsCached, nil
 }
 
 // Check if the destination channel already exists and thus needs a merger instead of new cloning.
-func (lifecycle *channelLifecycle) needsMerge(labelDst string) bool {
+func (lifecycle *channelLifecycle) needsMerge(labelDst string) (bool, error) {
+	channels, err := lifecycle.GetAllSoftwareChannels()
+	if err != nil {
+		return false, err
+	}
 	needs := false
-	for _, channelData := range lifecycle.GetAllSoftwareChannels() {
-		label := channelData.(map[string]interface{})["label"].(string)
-		if label == labelDst {
+	for _, channel := range channels {
+		if channel.Label == labelDst {
 			needs = true
 		}
 	}
 
-	return needs
+	return needs, nil
 }
 
 // Tell if the channel is filtered-out
@@ -184,69 +261,93 @@ func (lifecycle *channelLifecycle) isExcluded(label string) string {
 }
 
 // Merge channels
-func (lifecycle *channelLifecycle) MergeChannels(labelSrc string, labelDst string) {
+func (lifecycle *channelLifecycle) MergeChannels(labelSrc string, labelDst string) error {
 	if prefix := lifecycle.isFiltered(labelDst); prefix != "" {
-		utils.Console.ExitOnStderr(fmt.Sprintf("Channel \"%s\" is filtered-out in this workflow.",
-			strings.Replace(labelSrc, prefix, rgbterm.FgString(prefix, 0xff, 0xff, 0), 1)))
+		return fmt.Errorf("Channel \"%s\" is filtered-out in this workflow.",
+			strings.Replace(labelSrc, prefix, rgbterm.FgString(prefix, 0xff, 0xff, 0), 1))
 	} else if excl := lifecycle.isExcluded(labelSrc); excl != "" {
-		utils.Console.ExitOnStderr(fmt.Sprintf("Channel \"%s\" is marked as excluded by this workflow.",
-			strings.ReplaceAll(labelSrc, excl, rgbterm.FgString(excl, 0xff, 0xff, 0))))
+		return fmt.Errorf("Channel \"%s\" is marked as excluded by this workflow.",
+			strings.ReplaceAll(labelSrc, excl, rgbterm.FgString(excl, 0xff, 0xff, 0)))
 	}
 	if lifecycle.ctx.Bool("clear-channel") || lifecycle.ctx.Bool("rollback") {
-		lifecycle.ClearChannel(labelDst)
+		if err := lifecycle.ClearChannel(labelDst); err != nil {
+			return err
+		}
+	}
+	entry := utils.AuditEntry{Operation: "merge", Source: labelSrc, Target: labelDst, Counts: map[string]int{}}
+	var err error
+	lifecycle.logger.Info("Merging errata from channel \"%s\" to channel \"%s\"", labelSrc, labelDst)
+	if entry.Counts["errata"], err = lifecycle.api.MergeErrata(labelSrc, labelDst); err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(labelSrc, err))
+	}
+
+	lifecycle.logger.Info("Merging packages from channel \"%s\" to channel \"%s\"", labelSrc, labelDst)
+	if entry.Counts["packages"], err = lifecycle.api.MergePackages(labelSrc, labelDst); err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(labelSrc, err))
 	}
-	Logger.Info("Merging errata from channel \"%s\" to channel \"%s\"", labelSrc, labelDst)
-	utils.RPC.RequestFuction("channel.software.mergeErrata", utils.RPC.GetSession(), labelSrc, labelDst)
-	//Logger.Info("Added %i packages", len(packageList))
 
-	Logger.Info("Merging packages from channel \"%s\" to channel \"%s\"", labelSrc, labelDst)
-	utils.RPC.RequestFuction("channel.software.mergePackages", utils.RPC.GetSession(), labelSrc, labelDst)
+	return lifecycle.audit(entry, nil)
 }
 
 // Clears all the errata in this channel
-func (lifecycle *channelLifecycle) ClearChannel(label string) {
-	Logger.Debug("Clear all errata from \"%s\"", label)
-	buff := make([]string, 0)
-	for _, errata := range utils.RPC.RequestFuction("channel.software.listErrata", utils.RPC.GetSession(), label).([]interface{}) {
-		buff = append(buff, errata.(map[string]interface{})["advisory_name"].(string))
+func (lifecycle *channelLifecycle) ClearChannel(label string) error {
+	lifecycle.logger.Debug("Clear all errata from \"%s\"", label)
+	errata, err := lifecycle.api.ListErrata(label)
+	if err != nil {
+		return lifecycle.channelError(label, err)
+	}
+	advisories := make([]string, 0)
+	for _, erratum := range errata {
+		advisories = append(advisories, erratum.Advisory)
+	}
+	entry := utils.AuditEntry{Operation: "clear", Target: label, Counts: map[string]int{"errata": len(advisories)}}
+	if err := lifecycle.removeErrata(label, advisories); err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(label, err))
 	}
-	utils.RPC.RequestFuction("channel.software.removeErrata", utils.RPC.GetSession(), label, buff, false)
-	buff = nil
 
-	Logger.Debug("Remove all packages from \"%s\"", label)
-	for _, pkg := range utils.RPC.RequestFuction("channel.software.listAllPackages", utils.RPC.GetSession(), label).([]interface{}) {
-		buff = append(buff, pkg.(map[string]interface{})["id"].(string))
+	lifecycle.logger.Debug("Remove all packages from \"%s\"", label)
+	packages, err := lifecycle.api.ListAllPackages(label)
+	if err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(label, err))
+	}
+	ids := make([]int, 0)
+	for _, pkg := range packages {
+		ids = append(ids, pkg.ID)
+	}
+	entry.Counts["packages"] = len(ids)
+	if err := lifecycle.removePackages(label, ids); err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(label, err))
 	}
-	utils.RPC.RequestFuction("channel.software.removePackages", utils.RPC.GetSession(), label, buff)
 
+	return lifecycle.audit(entry, nil)
 }
 
 // Clone channel by label
-func (lifecycle *channelLifecycle) CloneChannel(labelSrc string, labelDst string, details map[string]interface{}) {
+func (lifecycle *channelLifecycle) CloneChannel(labelSrc string, labelDst string, details *uyuni.ChannelDetails) error {
 	if lifecycle.ctx.String("exclude-channel") != "" {
 		excludePattern := lifecycle.ctx.String("exclude-channel")
 		if strings.Contains(labelSrc, excludePattern) {
 			labelSrc = strings.ReplaceAll(labelSrc, excludePattern, rgbterm.FgString(excludePattern, 0xff, 0xff, 0))
-			Logger.Fatal("Seems like you wanted to exclude this channel (" + labelSrc + ")?")
+			return fmt.Errorf("Seems like you wanted to exclude this channel (%s)?", labelSrc)
 		}
 	}
-	sourceChannelLabel, exist := details["label"]
-	if !exist {
-		Logger.Fatal("Unable to get full data about the channel: label is missing")
+	sourceChannelLabel := details.Label
+	if sourceChannelLabel == "" {
+		return errors.New("Unable to get full data about the channel: label is missing")
 	}
 	cloneDetails := make(map[string]interface{})
 	cloneDetails["label"] = labelDst
 	cloneDetails["name"] = labelDst
-	cloneDetails["summary"] = details["summary"]
+	cloneDetails["summary"] = details.Summary
+	cloneDetails["parent_label"] = details.ParentLabel
 
-	if details["parent_channel_label"] != nil {
-		cloneDetails["parent_label"] = details["parent_channel_label"]
-	} else {
-		cloneDetails["parent_label"] = ""
+	lifecycle.logger.Debug("Cloning channel \"%s\" to \"%s\"", sourceChannelLabel, labelDst)
+	entry := utils.AuditEntry{Operation: "clone", Source: sourceChannelLabel, Target: labelDst}
+	if err := lifecycle.api.CloneChannel(sourceChannelLabel, cloneDetails, false); err != nil {
+		return lifecycle.audit(entry, lifecycle.channelError(sourceChannelLabel, err))
 	}
 
-	Logger.Debug("Getting details about channel \"%s\"", sourceChannelLabel.(string))
-	utils.RPC.RequestFuction("channel.software.clone", utils.RPC.GetSession(), sourceChannelLabel, cloneDetails, false)
+	return lifecycle.audit(entry, nil)
 }
 
 // MakeArchiveLabel creates label with "archive-YYYYMMDD" prefix
@@ -276,35 +377,115 @@ func (lifecycle *channelLifecycle) UnarchiveLabel(labelSrc string) (string, erro
 	return retLabel, err
 }
 
-// Merge or clone all children channels
-func (lifecycle *channelLifecycle) ProcessChildrenChannels(labelSrc string) {
-	childrenChannels := make([]string, 0)
-	for _, channelData := range lifecycle.GetAllSoftwareChannels() {
-		if channelData.(map[string]interface{})["parent_label"] != nil {
-			parentLabel := channelData.(map[string]interface{})["parent_label"].(string)
-			if parentLabel == labelSrc {
-				childrenChannels = append(childrenChannels, channelData.(map[string]interface{})["label"].(string))
-			}
+// Get labels of all child channels of the base channel
+func (lifecycle *channelLifecycle) childChannels(labelSrc string) ([]string, error) {
+	channels, err := lifecycle.GetAllSoftwareChannels()
+	if err != nil {
+		return nil, err
+	}
+	children := make([]string, 0)
+	for _, channel := range channels {
+		if channel.ParentLabel == labelSrc {
+			children = append(children, channel.Label)
 		}
 	}
 
-	var destinationChannelName string
-	var err error
-	for _, childChannelLabel := range childrenChannels {
-		if lifecycle.ctx.Bool("archive") {
-			destinationChannelName, err = lifecycle.MakeArchiveLabel(childChannelLabel)
-		} else if lifecycle.ctx.Bool("rollback") {
-			destinationChannelName, err = lifecycle.UnarchiveLabel(childChannelLabel)
-		} else {
-			destinationChannelName = lifecycle.promoteChannel(childChannelLabel, lifecycle.ctx.Bool("init"))
+	return children, nil
+}
+
+// Merge or clone the channel to the destination channel. Clone of the child channel belongs to the destination parent.
+// Messages about it have the channel, phase and operation fields.
+func (lifecycle *channelLifecycle) processChannel(labelSrc string, labelDst string, parentDst string) error {
+	merge, err := lifecycle.needsMerge(labelDst)
+	if err != nil {
+		return err
+	}
+
+	operation := "clone"
+	if merge {
+		operation = "merge"
+	}
+	worker := *lifecycle
+	worker.logger = lifecycle.logger.With("channel", labelDst).With("operation", operation)
+	if phase := lifecycle.extractPhaseName(labelDst); phase != "" {
+		worker.logger = worker.logger.With("phase", phase)
+	}
+	started := time.Now()
+
+	if merge {
+		err = worker.MergeChannels(labelSrc, labelDst)
+	} else {
+		var details *uyuni.ChannelDetails
+		if details, err = worker.GetChannelDetails(labelSrc); err == nil {
+			details.ParentLabel = parentDst
+			err = worker.CloneChannel(labelSrc, labelDst, details)
 		}
-		utils.Console.CheckError(err)
-		if lifecycle.needsMerge(destinationChannelName) {
-			lifecycle.MergeChannels(childChannelLabel, destinationChannelName)
-		} else {
-			lifecycle.CloneChannel(childChannelLabel, destinationChannelName, lifecycle.GetChannelDetails(childChannelLabel))
+	}
+	if err == nil {
+		worker.logger.With("duration", time.Since(started).Round(time.Millisecond)).Info("Channel \"%s\" processed from \"%s\"", labelDst, labelSrc)
+	}
+	return err
+}
+
+// Get a copy of the lifecycle, which logs into its own buffer. Used by concurrent workers.
+func (lifecycle *channelLifecycle) withBufferedLogger() *channelLifecycle {
+	worker := *lifecycle
+	worker.logger = lifecycle.logger.Buffered()
+
+	return &worker
+}
+
+// Merge or clone the child channel to its destination in the next phase, archive or rollback
+func (lifecycle *channelLifecycle) processChildChannel(childChannelLabel string, parentDst string) error {
+	destinationChannelName, err := lifecycle.destinationLabel(childChannelLabel)
+	if err != nil {
+		return err
+	}
+
+	return lifecycle.processChannel(childChannelLabel, destinationChannelName, parentDst)
+}
+
+/*
+Merge or clone all children channels under the destination parent. Up to "--jobs" children are processed in parallel,
+each worker logs into its own buffer, which is flushed in the order of the channels.
+Without "--tolerant" no more children are started after the first failure.
+Errors of all failed children are reported together.
+*/
+func (lifecycle *channelLifecycle) ProcessChildrenChannels(labelSrc string, labelDst string) error {
+	childrenChannels, err := lifecycle.childChannels(labelSrc)
+	if err != nil {
+		return err
+	}
+
+	workers := make([]*channelLifecycle, len(childrenChannels))
+	for idx := range workers {
+		workers[idx] = lifecycle.withBufferedLogger()
+	}
+
+	var failed int32
+	failures := make([]string, 0)
+	utils.Parallel(len(childrenChannels), lifecycle.ctx.Int("jobs"), func(idx int) error {
+		if atomic.LoadInt32(&failed) > 0 && !lifecycle.ctx.Bool("tolerant") {
+			return errors.New("not processed after a previous failure")
+		}
+		err := workers[idx].processChildChannel(childrenChannels[idx], labelDst)
+		if err != nil {
+			atomic.AddInt32(&failed, 1)
+		}
+		return err
+	}, func(idx int, err error) {
+		workers[idx].logger.Flush()
+		if err != nil {
+			lifecycle.logger.Error("Channel \"%s\": %s", childrenChannels[idx], err.Error())
+			failures = append(failures, fmt.Sprintf("  %s: %s", childrenChannels[idx], err.Error()))
 		}
+	})
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d child channels has not been processed:\n%s",
+			len(failures), len(childrenChannels), strings.Join(failures, "\n"))
 	}
+
+	return nil
 }
 
 // List available workflows
@@ -334,21 +515,7 @@ func (lifecycle *channelLifecycle) ListWorkflows() {
 
 // Returns a phase name from the given channel name
 func (lifecycle *channelLifecycle) extractPhaseName(channelName string) string {
-	phase := ""
-	hasPhase := false
-	for _, phase := range lifecycle.phases {
-		phase = phase + lifecycle.phasesDelimiter
-		if len(channelName) > len(phase) && strings.HasPrefix(channelName, phase) {
-			hasPhase = true
-			break
-		}
-	}
-	if hasPhase {
-		// Get phase
-		phase = strings.Split(channelName, lifecycle.phasesDelimiter)[0]
-	}
-
-	return phase
+	return lifecycle.workflow.PhaseOf(channelName)
 }
 
 /*
@@ -356,13 +523,13 @@ Get next phase for the channel from the configured workflow.
 If current phase is set to an empty string (e.g. not found in the name of the channel)
 and init is set to True, then first phase of the current workflow is used.
 */
-func (lifecycle *channelLifecycle) getNextPhase(currentPhase string, init bool) string {
+func (lifecycle *channelLifecycle) getNextPhase(currentPhase string, init bool) (string, error) {
 	phase := ""
 	if currentPhase == "" && init {
 		phase = lifecycle.phases[0]
 	} else {
 		if currentPhase == lifecycle.phases[len(lifecycle.phases)-1] {
-			Logger.Fatal("Error. Unable to rotate phase: reached last available already.")
+			return "", errors.New("Unable to rotate phase: reached last available already")
 		} else {
 			for i, lcPhase := range lifecycle.phases {
 				if lcPhase == currentPhase {
@@ -373,107 +540,98 @@ func (lifecycle *channelLifecycle) getNextPhase(currentPhase string, init bool)
 		}
 	}
 
-	return phase
+	return phase, nil
 }
 
-// Get workflow configuration or return default one.
-func (lifecycle *channelLifecycle) getWorkflowConfig(name string) *map[string]interface{} {
-	currentWorkflow := make(map[string]interface{})
-	configSections := utils.Configuration.GetConfig(lifecycle.ctx, "lifecycle")
-	lifecycleConfig, exist := (*configSections)["lifecycle"].(map[interface{}]interface{})
-	if exist {
-		workflowsConfig, exist := lifecycleConfig["workflows"]
-		if exist {
-			workflowsData := workflowsConfig.(map[interface{}]interface{})
-			for workflowName := range workflowsData {
-				if workflowName == name {
-					currentWorkflow[name] = workflowsData[name]
-					break
-				}
-			}
-		}
+// Check if specified channel exists
+func (lifecycle *channelLifecycle) GetChannelDetails(name string) (*uyuni.ChannelDetails, error) {
+	details, err := lifecycle.api.ChannelDetails(name)
+	if err != nil {
+		return nil, lifecycle.channelError(name, err)
 	}
-
-	return &currentWorkflow
+	return details, nil
 }
 
-// Check if specified channel exists
-func (lifecycle *channelLifecycle) GetChannelDetails(name string) map[string]interface{} {
-	stuff := utils.RPC.RequestFuction("channel.software.getDetails", utils.RPC.GetSession(), name)
-	return stuff.(map[string]interface{})
+// Describe the error of the channel operation, so the missing channels and permissions are reported clearly
+func (lifecycle *channelLifecycle) channelError(label string, err error) error {
+	if fault, ok := utils.AsFault(err); ok {
+		if fault.IsNotFound() {
+			return fmt.Errorf("Channel \"%s\" does not exist: %s", label, err.Error())
+		} else if fault.IsPermission() {
+			return fmt.Errorf("Not allowed to change channel \"%s\": %s", label, err.Error())
+		}
+	}
+	return err
 }
 
 // Find what workflow currently is used and setup the phases
 func (lifecycle *channelLifecycle) setCurrentWorkflow() *channelLifecycle {
-	currentWorkflowName := lifecycle.ctx.String("workflow")
-	if funk.Contains([]string{"", "default"}, currentWorkflowName) {
-		currentWorkflowName = "default"
-	}
-	configuredWorkflow := lifecycle.getWorkflowConfig(currentWorkflowName)
-	if len(*configuredWorkflow) == 0 {
-		Logger.Debug("Using preset default workflow: \"dev\", \"uat\", \"prod\".")
-		lifecycle.phases = []string{"dev", "uat", "prod"}
-	} else {
-		Logger.Debug("Using specified workflow: %s", currentWorkflowName)
-
-		cfgPhases, configured := (*configuredWorkflow)[currentWorkflowName].(map[interface{}]interface{})["phases"]
-		if configured && cfgPhases != nil {
-			lifecycle.phases = make([]string, len(cfgPhases.([]interface{})))
-			for i, v := range cfgPhases.([]interface{}) {
-				lifecycle.phases[i] = v.(string)
-			}
-		} else {
-			Logger.Fatal("Phases are not configured in this workflow, aborting.")
-		}
-
-		cfgExclude, configured := (*configuredWorkflow)[currentWorkflowName].(map[interface{}]interface{})["exclude"]
-		if configured && cfgExclude != nil {
-			lifecycle.excludedChannels = make([]string, len(cfgExclude.([]interface{})))
-			for i, v := range cfgExclude.([]interface{}) {
-				lifecycle.excludedChannels[i] = v.(string)
-			}
-		} else {
-			Logger.Info("No channels configured to be excluded, according to this workflow")
-		}
+	currentWorkflow, err := utils.NewWorkflow(lifecycle.ctx, lifecycle.ctx.String("workflow"))
+	if err != nil {
+		lifecycle.logger.Fatal(err.Error())
+	}
+	lifecycle.workflow = currentWorkflow
 
-		cfgFilter, configured := (*configuredWorkflow)[currentWorkflowName].(map[interface{}]interface{})["filter"]
-		if configured && cfgFilter != nil {
-			lifecycle.filterChannels = make([]string, len(cfgFilter.([]interface{})))
-			for i, v := range cfgFilter.([]interface{}) {
-				lifecycle.filterChannels[i] = v.(string)
-			}
-		} else {
-			Logger.Info("No channels configured to be filtered by prefix, according to this workflow")
+	if currentWorkflow.IsPreset() {
+		lifecycle.logger.Debug("Using preset default workflow: \"dev\", \"uat\", \"prod\".")
+	} else {
+		lifecycle.logger.Debug("Using specified workflow: %s", currentWorkflow.Name())
+		if len(currentWorkflow.Excluded()) == 0 {
+			lifecycle.logger.Info("No channels configured to be excluded, according to this workflow")
 		}
-
-		// Set delimiter
-		cfgDelimiter, configured := (*configuredWorkflow)[currentWorkflowName].(map[interface{}]interface{})["delimiter"]
-		if configured && cfgDelimiter != nil {
-			lifecycle.phasesDelimiter = cfgDelimiter.(string)
-		} else {
-			lifecycle.phasesDelimiter = "-"
+		if len(currentWorkflow.Filtered()) == 0 {
+			lifecycle.logger.Info("No channels configured to be filtered by prefix, according to this workflow")
 		}
 	}
+	lifecycle.phases = currentWorkflow.Phases()
+	lifecycle.excludedChannels = currentWorkflow.Excluded()
+	lifecycle.filterChannels = currentWorkflow.Filtered()
+	lifecycle.phasesDelimiter = currentWorkflow.Delimiter()
+
 	return lifecycle
 }
 
 // Set flags from CLI and configuration about current runtime session
-func (lifecycle *channelLifecycle) setCurrentConfig() *channelLifecycle {
-	if lifecycle.ctx.GlobalBool("quiet") && lifecycle.ctx.GlobalBool("verbose") {
-		utils.Console.ExitOnUnknown("Don't know how to be quietly verbose.")
+func (lifecycle *channelLifecycle) SetCurrentConfig() *channelLifecycle {
+	lifecycle.logger.Debug("Configuration set")
+
+	return lifecycle
+}
+
+// ProcessChannelTree initialises, promotes, archives or rolls back the channel with its children, as selected
+// by the options, on this or another server. Returns label of the destination channel.
+func (lifecycle *channelLifecycle) ProcessChannelTree(channelToPromote string) (string, error) {
+	destinationChannelName, err := lifecycle.destinationLabel(channelToPromote)
+	if err != nil {
+		return "", err
 	}
 
-	Logger = *utils.NewLoggerController(lifecycle.ctx.GlobalBool("verbose"), lifecycle.ctx.GlobalBool("verbose"),
-		!lifecycle.ctx.GlobalBool("quiet"), lifecycle.ctx.GlobalBool("verbose"))
-	Logger.Debug("Configuration set")
+	if server := lifecycle.ctx.String("to-server"); server != "" {
+		return destinationChannelName, lifecycle.PromoteToServer(channelToPromote, destinationChannelName, server)
+	}
 
-	return lifecycle
+	parentDst, err := lifecycle.parentDestination(channelToPromote)
+	if err != nil {
+		return "", err
+	}
+	if err := lifecycle.processChannel(channelToPromote, destinationChannelName, parentDst); err != nil {
+		return "", err
+	}
+	// Process also child channels
+	if !lifecycle.ctx.Bool("no-children") {
+		if err := lifecycle.ProcessChildrenChannels(channelToPromote, destinationChannelName); err != nil {
+			return "", err
+		}
+	}
+
+	return destinationChannelName, nil
 }
 
 // Entry action for the managing channel lifecycle sub-app
 func ManageChannelLifecycle(ctx *cli.Context) error {
-	lifecycle := NewChannelLifecycle(ctx).setCurrentConfig().setCurrentWorkflow()
-	utils.RPC.Connect((*utils.Configuration.GetConfig(ctx, "server")))
+	lifecycle := NewChannelLifecycle(ctx).SetCurrentConfig().setCurrentWorkflow()
+	utils.RefreshCache()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
 
 	if ctx.Bool("promote") || ctx.Bool("init") || ctx.Bool("archive") {
 		if ctx.String("channel") == "" {
@@ -485,30 +643,14 @@ func ManageChannelLifecycle(ctx *cli.Context) error {
 		lifecycle.ListWorkflows()
 	} else if ctx.Bool("promote") || ctx.Bool("init") || ctx.Bool("archive") || ctx.Bool("rollback") {
 		channelToPromote := ctx.String("channel")
-		var destinationChannelName string
-		var err error
-
-		if ctx.Bool("archive") {
-			destinationChannelName, err = lifecycle.MakeArchiveLabel(channelToPromote)
-		} else if ctx.Bool("rollback") {
-			destinationChannelName, err = lifecycle.UnarchiveLabel(channelToPromote)
-		} else {
-			destinationChannelName = lifecycle.promoteChannel(channelToPromote, ctx.Bool("init"))
-		}
+		destinationChannelName, err := lifecycle.ProcessChannelTree(channelToPromote)
 		utils.Console.CheckError(err)
 
-		if lifecycle.needsMerge(destinationChannelName) {
-			lifecycle.MergeChannels(channelToPromote, destinationChannelName)
-		} else {
-			lifecycle.CloneChannel(channelToPromote, destinationChannelName, lifecycle.GetChannelDetails(channelToPromote))
+		if ctx.String("to-server") != "" {
+			lifecycle.logger.Info("Channel \"%s\" promoted to \"%s\" on server \"%s\"\n", channelToPromote, destinationChannelName, ctx.String("to-server"))
+			return nil
 		}
-
-		// Process also child channels
-		if !ctx.Bool("no-children") {
-			lifecycle.ProcessChildrenChannels(channelToPromote)
-		}
-
-		Logger.Info("Channel \"%s\" promoted to \"%s\"\n", channelToPromote, destinationChannelName)
+		lifecycle.logger.Info("Channel \"%s\" promoted to \"%s\"\n", channelToPromote, destinationChannelName)
 		app_info.NewInfoCmd(ctx).SetCurrentConfig().ChannelDetails(destinationChannelName)
 	} else {
 		utils.Console.ExitOnUnknown("Don't know what to do.")
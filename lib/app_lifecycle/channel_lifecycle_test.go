@@ -0,0 +1,250 @@
+package app_lifecycle
+
+import (
+	"flag"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// State of the fake server: "sles" base channel with "sles-updates" child and "extra" channel with an erratum to merge
+const testState = `{
+  "channels": [
+    {"id": 1, "label": "sles", "name": "sles", "arch_label": "channel-x86_64", "packages": [1, 2, 3], "errata": ["SUSE-1"]},
+    {"id": 2, "label": "sles-updates", "name": "sles-updates", "arch_label": "channel-x86_64", "parent_label": "sles",
+     "packages": [4], "errata": ["SUSE-2"]},
+    {"id": 3, "label": "extra", "name": "extra", "arch_label": "channel-x86_64", "packages": [6], "errata": ["SUSE-3"]}
+  ],
+  "packages": [
+    {"id": 1, "name": "bash", "version": "4.4", "release": "1", "arch_label": "x86_64"},
+    {"id": 2, "name": "curl", "version": "7.66", "release": "1", "arch_label": "x86_64"},
+    {"id": 3, "name": "vim", "version": "8.0", "release": "1", "arch_label": "x86_64"},
+    {"id": 4, "name": "vim", "version": "8.0", "release": "2", "arch_label": "x86_64"},
+    {"id": 5, "name": "zsh", "version": "5.6", "release": "1", "arch_label": "x86_64"},
+    {"id": 6, "name": "openssl", "version": "1.1.1", "release": "1", "arch_label": "x86_64"}
+  ],
+  "errata": [
+    {"id": 1, "advisory": "SUSE-1", "type": "Security Advisory", "issue_date": "2020-01-10", "packages": [3]},
+    {"id": 2, "advisory": "SUSE-2", "type": "Bug Fix Advisory", "issue_date": "2020-02-10", "packages": [4]},
+    {"id": 3, "advisory": "SUSE-3", "type": "Security Advisory", "issue_date": "2020-03-10", "packages": [6]}
+  ],
+  "last_id": 100
+}`
+
+// Requester, which counts the calls passed to the connected backend
+type countingRequester struct {
+	calls map[string]int
+	lock  sync.Mutex
+}
+
+func (counter *countingRequester) RequestFuction(name string, args ...interface{}) (interface{}, error) {
+	counter.lock.Lock()
+	counter.calls[name]++
+	counter.lock.Unlock()
+
+	return utils.RPC.RequestFuction(name, args...)
+}
+
+// Create configuration of the fake server with the test state and the audit log in a temporary directory.
+// Returns the configuration file and the function, which removes the directory.
+func setupFakeServer(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "spaceman-lifecycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateFile := filepath.Join(dir, "state.json")
+	if err := ioutil.WriteFile(stateFile, []byte(testState), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.conf")
+	content := "server:\n  backend: fake\n  user: admin\n  state_file: " + stateFile + "\n"
+	if err := ioutil.WriteFile(config, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	utils.Audit.SetFile(filepath.Join(dir, "audit.log"))
+
+	return config, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// Parse options of the lifecycle, connect to the fake server and process the channel tree
+func runLifecycle(t *testing.T, config string, args ...string) (string, *countingRequester, error) {
+	app := cli.NewApp()
+	globalSet := flag.NewFlagSet("spaceman", flag.ContinueOnError)
+	globalSet.String("config", "", "")
+	globalSet.String("server", "", "")
+	globalSet.Bool("quiet", true, "")
+	if err := globalSet.Parse([]string{"--config", config}); err != nil {
+		t.Fatal(err)
+	}
+	set := flag.NewFlagSet("lc", flag.ContinueOnError)
+	for _, lifecycleFlag := range ChannelLifecycleFlags {
+		lifecycleFlag.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cli.NewContext(app, set, cli.NewContext(app, globalSet, nil))
+
+	lifecycle := NewChannelLifecycle(ctx).setCurrentWorkflow()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	counter := &countingRequester{calls: make(map[string]int)}
+	lifecycle.api = uyuni.NewClient(counter)
+
+	label, err := lifecycle.ProcessChannelTree(ctx.String("channel"))
+	return label, counter, err
+}
+
+// Get sorted IDs of the channel packages
+func packageIDs(t *testing.T, label string) []int {
+	packages, err := uyuni.NewClient(utils.RPC).ListAllPackages(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, 0, len(packages))
+	for _, pkg := range packages {
+		ids = append(ids, pkg.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Get sorted advisories of the channel errata
+func advisories(t *testing.T, label string) []string {
+	errata, err := uyuni.NewClient(utils.RPC).ListErrata(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(errata))
+	for _, erratum := range errata {
+		names = append(names, erratum.Advisory)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func assertEqual(t *testing.T, what string, expected interface{}, actual interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("%s: expected %v, got %v", what, expected, actual)
+	}
+}
+
+func TestInitClonesChannelTree(t *testing.T) {
+	config, cleanup := setupFakeServer(t)
+	defer cleanup()
+
+	label, _, err := runLifecycle(t, config, "--init", "--channel", "sles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "destination", "dev-sles", label)
+
+	client := uyuni.NewClient(utils.RPC)
+	for _, clone := range []struct{ label, original, parent string }{
+		{"dev-sles", "sles", ""},
+		{"dev-sles-updates", "sles-updates", "dev-sles"},
+	} {
+		details, err := client.ChannelDetails(clone.label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, clone.label+" original", clone.original, details.CloneOriginal)
+		assertEqual(t, clone.label+" parent", clone.parent, details.ParentLabel)
+	}
+	assertEqual(t, "dev-sles packages", []int{1, 2, 3}, packageIDs(t, "dev-sles"))
+	assertEqual(t, "dev-sles errata", []string{"SUSE-1"}, advisories(t, "dev-sles"))
+	assertEqual(t, "dev-sles-updates packages", []int{4}, packageIDs(t, "dev-sles-updates"))
+	assertEqual(t, "dev-sles-updates errata", []string{"SUSE-2"}, advisories(t, "dev-sles-updates"))
+
+	if _, _, err := runLifecycle(t, config, "--init", "--channel", "dev-sles"); err == nil {
+		t.Error("initialising of an initialised channel should fail")
+	}
+}
+
+func TestPromoteMergesPackagesAndErrata(t *testing.T) {
+	config, cleanup := setupFakeServer(t)
+	defer cleanup()
+
+	for _, args := range [][]string{{"--init", "--channel", "sles"}, {"--promote", "--channel", "dev-sles"}} {
+		if _, _, err := runLifecycle(t, config, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertEqual(t, "uat-sles packages", []int{1, 2, 3}, packageIDs(t, "uat-sles"))
+	assertEqual(t, "uat-sles-updates errata", []string{"SUSE-2"}, advisories(t, "uat-sles-updates"))
+
+	client := uyuni.NewClient(utils.RPC)
+	if err := client.AddPackages("dev-sles", []int{5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.MergeErrata("extra", "dev-sles", "SUSE-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	label, counter, err := runLifecycle(t, config, "--promote", "--channel", "dev-sles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "destination", "uat-sles", label)
+	assertEqual(t, "uat-sles packages", []int{1, 2, 3, 5, 6}, packageIDs(t, "uat-sles"))
+	assertEqual(t, "uat-sles errata", []string{"SUSE-1", "SUSE-3"}, advisories(t, "uat-sles"))
+	assertEqual(t, "uat-sles-updates packages", []int{4}, packageIDs(t, "uat-sles-updates"))
+	assertEqual(t, "clone calls", 0, counter.calls["channel.software.clone"])
+	assertEqual(t, "merge errata calls", 2, counter.calls["channel.software.mergeErrata"])
+	assertEqual(t, "merge packages calls", 2, counter.calls["channel.software.mergePackages"])
+}
+
+func TestPromoteClearsChannelInBatches(t *testing.T) {
+	config, cleanup := setupFakeServer(t)
+	defer cleanup()
+
+	for _, args := range [][]string{{"--init", "--channel", "sles"}, {"--promote", "--channel", "dev-sles"}} {
+		if _, _, err := runLifecycle(t, config, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := uyuni.NewClient(utils.RPC)
+	if err := client.AddPackages("uat-sles", []int{5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.MergeErrata("extra", "uat-sles", "SUSE-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, counter, err := runLifecycle(t, config, "--promote", "--channel", "dev-sles", "--clear-channel", "--batch-size", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "uat-sles packages", []int{1, 2, 3}, packageIDs(t, "uat-sles"))
+	assertEqual(t, "uat-sles errata", []string{"SUSE-1"}, advisories(t, "uat-sles"))
+	assertEqual(t, "uat-sles-updates packages", []int{4}, packageIDs(t, "uat-sles-updates"))
+	assertEqual(t, "uat-sles-updates errata", []string{"SUSE-2"}, advisories(t, "uat-sles-updates"))
+
+	// One call per item: SUSE-1, SUSE-3 and SUSE-2 errata, packages 1, 2, 3, 5, 6 and 4
+	assertEqual(t, "remove errata calls", 3, counter.calls["channel.software.removeErrata"])
+	assertEqual(t, "remove packages calls", 6, counter.calls["channel.software.removePackages"])
+
+	entries, err := utils.Audit.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleared := make(map[string]map[string]int)
+	for _, entry := range entries {
+		if entry.Operation == "clear" && entry.Result == utils.AuditSuccess {
+			cleared[entry.Target] = entry.Counts
+		}
+	}
+	assertEqual(t, "cleared channels", map[string]map[string]int{
+		"uat-sles":         {"errata": 2, "packages": 5},
+		"uat-sles-updates": {"errata": 1, "packages": 1},
+	}, cleared)
+}
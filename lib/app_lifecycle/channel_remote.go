@@ -0,0 +1,120 @@
+package app_lifecycle
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/utils"
+)
+
+// Create channel on the remote server with the same metadata as the source channel
+func (lifecycle *channelLifecycle) createRemoteChannel(remote *channelLifecycle, labelSrc string, labelDst string, parentDst string) error {
+	details, err := lifecycle.GetChannelDetails(labelSrc)
+	if err != nil {
+		return err
+	}
+	lifecycle.logger.Info("Creating channel \"%s\" on the target server", labelDst)
+	err = remote.api.CreateChannel(labelDst, labelDst, details.Summary, details.ArchLabel, parentDst, details.ChecksumLabel)
+	return remote.audit(utils.AuditEntry{Operation: "create", Source: labelSrc, Target: labelDst}, err)
+}
+
+// Promote content of one channel to the channel on the remote server. Returns true if all content is promoted.
+func (lifecycle *channelLifecycle) promoteRemoteChannel(remote *channelLifecycle, labelSrc string, labelDst string, parentDst string) (bool, error) {
+	merge, err := remote.needsMerge(labelDst)
+	if err != nil {
+		return false, err
+	}
+	if merge {
+		if lifecycle.ctx.Bool("clear-channel") {
+			err = remote.ClearChannel(labelDst)
+		}
+	} else {
+		err = lifecycle.createRemoteChannel(remote, labelSrc, labelDst, parentDst)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	entry := utils.AuditEntry{Operation: "promote", Source: labelSrc, Target: labelDst, Counts: map[string]int{}}
+	complete, err := lifecycle.promoteRemoteContent(remote, labelSrc, labelDst, entry.Counts)
+	return complete, remote.audit(entry, err)
+}
+
+// Add packages and errata of the channel to the channel on the remote server, counting them
+func (lifecycle *channelLifecycle) promoteRemoteContent(remote *channelLifecycle, labelSrc string, labelDst string, counts map[string]int) (bool, error) {
+	complete := true
+	lifecycle.logger.Info("Adding packages from channel \"%s\" to channel \"%s\" on the target server", labelSrc, labelDst)
+	packages, err := lifecycle.ChannelPackages(labelSrc)
+	if err != nil {
+		return false, err
+	}
+	missingPackages, err := remote.AddPackages(labelDst, packages)
+	if err != nil {
+		return false, err
+	}
+	counts["packages"], counts["missing_packages"] = len(packages), len(missingPackages)
+	for _, ref := range missingPackages {
+		fmt.Printf("Channel \"%s\": package %s (%s %s) must be synced via ISS first\n", labelDst, ref, ref.ChecksumType, ref.Checksum)
+		complete = false
+	}
+
+	if !lifecycle.ctx.Bool("no-errata") {
+		errata, err := lifecycle.ChannelErrata(labelSrc)
+		if err != nil {
+			return false, err
+		}
+		advisories := make([]string, 0)
+		for _, ref := range errata {
+			advisories = append(advisories, ref.Advisory)
+		}
+		lifecycle.logger.Info("Adding errata from channel \"%s\" to channel \"%s\" on the target server", labelSrc, labelDst)
+		missingErrata, err := remote.AddErrata(labelDst, advisories)
+		if err != nil {
+			return false, err
+		}
+		counts["errata"], counts["missing_errata"] = len(advisories), len(missingErrata)
+		for _, advisory := range missingErrata {
+			fmt.Printf("Channel \"%s\": erratum %s must be synced via ISS first\n", labelDst, advisory)
+			complete = false
+		}
+	}
+
+	return complete, nil
+}
+
+// PromoteToServer promotes channel with its children to the channel on another configured server.
+// Packages are verified on the target server by their checksums. Content that is missing
+// on the target server is reported, so it can be synchronised via Inter-Server Sync first.
+func (lifecycle *channelLifecycle) PromoteToServer(labelSrc string, labelDst string, server string) error {
+	name, serverConfig := utils.Configuration.GetNamedServerConfig(lifecycle.ctx, server)
+	target := utils.NewBackend(name, serverConfig)
+	user, _ := serverConfig["user"].(string)
+	remote := lifecycle.onServer(target, target.GetURL(), user)
+	lifecycle.logger.Info("Promoting channel \"%s\" to channel \"%s\" on server \"%s\"", labelSrc, labelDst, server)
+
+	complete, err := lifecycle.promoteRemoteChannel(remote, labelSrc, labelDst, "")
+	if err != nil {
+		return err
+	}
+	if !lifecycle.ctx.Bool("no-children") {
+		children, err := lifecycle.childChannels(labelSrc)
+		if err != nil {
+			return err
+		}
+		for _, childSrc := range children {
+			childDst, err := lifecycle.destinationLabel(childSrc)
+			if err != nil {
+				return err
+			}
+			childComplete, err := lifecycle.promoteRemoteChannel(remote, childSrc, childDst, labelDst)
+			if err != nil {
+				return err
+			}
+			complete = complete && childComplete
+		}
+	}
+
+	if !complete {
+		return fmt.Errorf("Channel \"%s\" is not completely promoted to server \"%s\"", labelSrc, server)
+	}
+
+	return nil
+}
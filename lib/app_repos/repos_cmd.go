@@ -0,0 +1,250 @@
+package app_repos
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/outputters"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/urfave/cli"
+	"sort"
+	"time"
+)
+
+var ReposCmdFlags []cli.Flag
+
+func init() {
+	ReposCmdFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:   "l, list",
+			Usage:  "list repositories, or only those of the channel, if --channel is specified",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "create",
+			Usage:  "create a repository with --repo label, --url and --type",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "update",
+			Usage:  "update URL and/or label of the repository",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "associate",
+			Usage:  "associate the repository with the channel",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "disassociate",
+			Usage:  "disassociate the repository from the channel",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "schedule",
+			Usage: "set Quartz cron expression to sync the channel repositories, e.g. \"0 0 23 ? * MON-FRI\"",
+		},
+		cli.BoolFlag{
+			Name:   "sync",
+			Usage:  "trigger synchronisation of the channel repositories",
+			Hidden: false,
+		},
+		cli.BoolFlag{
+			Name:   "wait",
+			Usage:  "wait until the triggered synchronisation is finished",
+			Hidden: false,
+		},
+		cli.StringFlag{
+			Name:  "c, channel",
+			Usage: "channel label",
+		},
+		cli.StringFlag{
+			Name:  "r, repo",
+			Usage: "repository label",
+		},
+		cli.StringFlag{
+			Name:  "u, url",
+			Usage: "repository URL",
+		},
+		cli.StringFlag{
+			Name:  "new-label",
+			Usage: "new label of the repository when updating",
+		},
+		cli.StringFlag{
+			Name:  "t, type",
+			Usage: "repository type",
+			Value: "yum",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "give up waiting for synchronisation after this time",
+			Value: time.Hour,
+		},
+		cli.DurationFlag{
+			Name:  "interval",
+			Usage: "interval of polling the synchronisation status",
+			Value: 30 * time.Second,
+		},
+	}
+}
+
+type reposCmd struct {
+	api *uyuni.Client
+	ctx *cli.Context
+}
+
+// NewReposCmd constructor
+func NewReposCmd(ctx *cli.Context) *reposCmd {
+	cmd := new(reposCmd)
+	cmd.ctx = ctx
+	cmd.api = uyuni.NewClient(utils.RPC)
+	return cmd
+}
+
+// ListRepos prints all repositories or only the repositories of the channel
+func (cmd *reposCmd) ListRepos(channel string) {
+	var out []uyuni.ContentSource
+	var err error
+	if channel != "" {
+		out, err = cmd.api.ListChannelRepos(channel)
+		utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
+	} else {
+		out, err = cmd.api.ListUserRepos()
+		utils.Console.CheckError(err)
+	}
+
+	rows := make([][]interface{}, 0)
+	for _, repo := range out {
+		rows = append(rows, []interface{}{repo.Label, repo.Type, repo.URL})
+	}
+	if len(rows) == 0 {
+		utils.Console.ExitOnStderr("No repositories has been found")
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return fmt.Sprint(rows[i][0]) < fmt.Sprint(rows[j][0])
+	})
+	fmt.Println()
+	outputters.NewAnsiCLI().Table([]string{"Label", "Type", "URL"}, rows)
+}
+
+// Target of the repository operation in the audit log
+func repoTarget(label string) string {
+	return fmt.Sprintf("repository %s", label)
+}
+
+// CreateRepo creates a new repository
+func (cmd *reposCmd) CreateRepo(label string, repoType string, url string) {
+	utils.Log.Info("Creating %s repository \"%s\" at %s", repoType, label, url)
+	err := cmd.api.CreateRepo(label, repoType, url)
+	utils.Audit.Record(utils.AuditEntry{Operation: "create", Source: url, Target: repoTarget(label)}, err)
+	utils.Console.CheckError(err)
+}
+
+// UpdateRepo changes URL and/or label of the repository
+func (cmd *reposCmd) UpdateRepo(label string, url string, newLabel string) {
+	if url != "" {
+		utils.Log.Info("Updating URL of repository \"%s\" to %s", label, url)
+		err := cmd.api.UpdateRepoURL(label, url)
+		utils.Audit.Record(utils.AuditEntry{Operation: "update", Source: url, Target: repoTarget(label)}, err)
+		utils.Console.CheckFault(err, fmt.Sprintf("Repository \"%s\"", label))
+	}
+	if newLabel != "" {
+		utils.Log.Info("Renaming repository \"%s\" to \"%s\"", label, newLabel)
+		err := cmd.api.UpdateRepoLabel(label, newLabel)
+		utils.Audit.Record(utils.AuditEntry{Operation: "rename", Source: repoTarget(label), Target: repoTarget(newLabel)}, err)
+		utils.Console.CheckFault(err, fmt.Sprintf("Repository \"%s\"", label))
+	}
+}
+
+// AssociateRepo associates repository with the channel or disassociates it
+func (cmd *reposCmd) AssociateRepo(channel string, label string, associate bool) {
+	var err error
+	operation := "associate"
+	if associate {
+		utils.Log.Info("Associating repository \"%s\" with channel \"%s\"", label, channel)
+		err = cmd.api.AssociateRepo(channel, label)
+	} else {
+		utils.Log.Info("Disassociating repository \"%s\" from channel \"%s\"", label, channel)
+		err = cmd.api.DisassociateRepo(channel, label)
+		operation = "disassociate"
+	}
+	utils.Audit.Record(utils.AuditEntry{Operation: operation, Source: repoTarget(label), Target: channel}, err)
+	utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\" or repository \"%s\"", channel, label))
+}
+
+// ScheduleSync sets cron schedule of the channel repositories synchronisation
+func (cmd *reposCmd) ScheduleSync(channel string, cronExpr string) {
+	utils.Log.Info("Scheduling synchronisation of channel \"%s\" at \"%s\"", channel, cronExpr)
+	err := cmd.api.SyncRepo(channel, cronExpr)
+	utils.Audit.Record(utils.AuditEntry{Operation: "schedule", Source: cronExpr, Target: channel}, err)
+	utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
+}
+
+// Get last synchronisation time of the channel
+func (cmd *reposCmd) lastSync(channel string) string {
+	details, err := cmd.api.ChannelDetails(channel)
+	utils.Console.CheckFault(err, fmt.Sprintf("Channel \"%s\"", channel))
+	return details.LastSync
+}
+
+// SyncRepos triggers synchronisation of the channel repositories and optionally waits until it is finished
+func (cmd *reposCmd) SyncRepos(channel string, wait bool) {
+	lastSync := cmd.lastSync(channel)
+	utils.Log.Info("Triggering synchronisation of channel \"%s\"", channel)
+	err := cmd.api.SyncRepo(channel)
+	utils.Audit.Record(utils.AuditEntry{Operation: "sync", Target: channel}, err)
+	utils.Console.CheckError(err)
+	if !wait {
+		return
+	}
+
+	deadline := time.Now().Add(cmd.ctx.Duration("timeout"))
+	for {
+		time.Sleep(cmd.ctx.Duration("interval"))
+		if current := cmd.lastSync(channel); current != lastSync {
+			fmt.Printf("Channel \"%s\" synchronised at %s\n", channel, current)
+			return
+		}
+		if time.Now().After(deadline) {
+			utils.Console.ExitOnStderr(fmt.Sprintf("Timed out waiting for synchronisation of channel \"%s\"", channel))
+		}
+		utils.Log.Debug("Still waiting for synchronisation of channel \"%s\"", channel)
+	}
+}
+
+// Entry action for the repos sub-app
+func MainReposCmd(ctx *cli.Context) error {
+	cmd := NewReposCmd(ctx)
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+
+	channel, repo := ctx.String("channel"), ctx.String("repo")
+	if (ctx.Bool("create") || ctx.Bool("update") || ctx.Bool("associate") || ctx.Bool("disassociate")) && repo == "" {
+		utils.Console.ExitOnUnknown("Repository label required.")
+	}
+	if (ctx.Bool("associate") || ctx.Bool("disassociate") || ctx.Bool("sync") || ctx.String("schedule") != "") && channel == "" {
+		utils.Console.ExitOnUnknown("Channel required.")
+	}
+
+	if ctx.Bool("list") {
+		cmd.ListRepos(channel)
+	} else if ctx.Bool("create") {
+		if ctx.String("url") == "" {
+			utils.Console.ExitOnUnknown("Repository URL required.")
+		}
+		cmd.CreateRepo(repo, ctx.String("type"), ctx.String("url"))
+		if channel != "" {
+			cmd.AssociateRepo(channel, repo, true)
+		}
+	} else if ctx.Bool("update") {
+		cmd.UpdateRepo(repo, ctx.String("url"), ctx.String("new-label"))
+	} else if ctx.Bool("associate") || ctx.Bool("disassociate") {
+		cmd.AssociateRepo(channel, repo, ctx.Bool("associate"))
+	} else if ctx.String("schedule") != "" {
+		cmd.ScheduleSync(channel, ctx.String("schedule"))
+	} else if ctx.Bool("sync") {
+		cmd.SyncRepos(channel, ctx.Bool("wait"))
+	} else {
+		utils.Console.ExitOnUnknown("Don't know what to do.")
+	}
+
+	return nil
+}
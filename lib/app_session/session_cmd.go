@@ -0,0 +1,25 @@
+package app_session
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/urfave/cli"
+)
+
+// Entry action for the login sub-app
+func MainLoginCmd(ctx *cli.Context) error {
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	utils.Console.CheckError(utils.RPC.Login())
+	fmt.Printf("Logged in to %s\n", utils.RPC.GetURL())
+
+	return nil
+}
+
+// Entry action for the logout sub-app
+func MainLogoutCmd(ctx *cli.Context) error {
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+	utils.Console.CheckError(utils.RPC.Logout())
+	fmt.Printf("Logged out from %s\n", utils.RPC.GetURL())
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package app_systems
+
+import (
+	"fmt"
+	"github.com/isbm/spaceman/lib/app_info"
+	"github.com/isbm/spaceman/lib/utils"
+	"github.com/isbm/spaceman/lib/uyuni"
+	"github.com/thoas/go-funk"
+	"github.com/urfave/cli"
+	"strings"
+)
+
+var SystemsCmdFlags []cli.Flag
+
+func init() {
+	SystemsCmdFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "s, system",
+			Usage: "comma-separated list of system IDs or names",
+		},
+		cli.StringFlag{
+			Name:  "g, group",
+			Usage: "apply to all systems of the system group",
+		},
+		cli.StringFlag{
+			Name:  "b, base-channel",
+			Usage: "subscribe systems to the base channel",
+		},
+		cli.StringFlag{
+			Name:  "c, child-channels",
+			Usage: "comma-separated list of child channels to subscribe systems to",
+		},
+		cli.StringFlag{
+			Name:  "p, phase",
+			Usage: "move systems to the same channels of another phase",
+		},
+		cli.StringFlag{
+			Name:  "w, workflow",
+			Usage: "use configured workflow",
+		},
+		cli.BoolFlag{
+			Name:   "n, dry-run",
+			Usage:  "don't perform any real operations",
+			Hidden: false,
+		},
+	}
+}
+
+type systemsCmd struct {
+	workflow *utils.Workflow
+	channels utils.ChannelLabels
+	ctx      *cli.Context
+}
+
+// NewSystemsCmd constructor
+func NewSystemsCmd(ctx *cli.Context) *systemsCmd {
+	cmd := new(systemsCmd)
+	cmd.ctx = ctx
+	return cmd
+}
+
+// Get IDs of all systems to be changed
+func (cmd *systemsCmd) TargetSystems() []int {
+	systems := make([]int, 0)
+	for _, idOrName := range utils.SplitList(cmd.ctx.String("system")) {
+		sid, err := app_info.ResolveSystem(idOrName)
+		utils.Console.CheckError(err)
+		systems = append(systems, sid)
+	}
+
+	if group := cmd.ctx.String("group"); group != "" {
+		groupSystems, err := utils.AsStructs(utils.RPC.RequestFuction("systemgroup.listSystemsMinimal", group))
+		if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+			utils.Console.ExitOnStderr(fmt.Sprintf("System group \"%s\" does not exist", group))
+		}
+		utils.Console.CheckError(err)
+		for _, system := range groupSystems {
+			if sid, ok := system["id"].(int); ok {
+				systems = append(systems, sid)
+			}
+		}
+	}
+
+	return funk.UniqInt(systems)
+}
+
+// Get current base channel and child channels of the system
+func (cmd *systemsCmd) subscriptions(sid int) (string, []string) {
+	base, children, err := uyuni.NewClient(utils.RPC).SubscribedChannels(sid)
+	utils.Console.CheckError(err)
+
+	return base, children
+}
+
+// SetChannels subscribes system to the base channel (if not empty) and child channels (if not nil)
+func (cmd *systemsCmd) SetChannels(sid int, base string, children []string) {
+	if base != "" {
+		utils.Log.Info("Setting base channel of system %v to \"%s\"", sid, base)
+		if !cmd.ctx.Bool("dry-run") {
+			_, err := utils.RPC.RequestFuction("system.setBaseChannel", sid, base)
+			cmd.audit(sid, []string{base}, err)
+			utils.Console.CheckError(err)
+		}
+	}
+	if children != nil {
+		utils.Log.Info("Setting child channels of system %v to \"%s\"", sid, strings.Join(children, "\", \""))
+		if !cmd.ctx.Bool("dry-run") {
+			_, err := utils.RPC.RequestFuction("system.setChildChannels", sid, children)
+			cmd.audit(sid, children, err)
+			utils.Console.CheckError(err)
+		}
+	}
+}
+
+// Record subscription of the system to the channels in the audit log
+func (cmd *systemsCmd) audit(sid int, channels []string, err error) {
+	utils.Audit.Record(utils.AuditEntry{Workflow: cmd.workflow.Name(), Operation: "subscribe", Source: strings.Join(channels, ","),
+		Target: fmt.Sprintf("system %d", sid), Counts: map[string]int{"channels": len(channels)}}, err)
+}
+
+// MoveToPhase subscribes system to the same channels in another phase of the workflow
+func (cmd *systemsCmd) MoveToPhase(sid int, phase string) {
+	base, children := cmd.subscriptions(sid)
+	if base == "" {
+		utils.Console.ExitOnStderr(fmt.Sprintf("System %d is not subscribed to any base channel", sid))
+	}
+
+	targetBase, err := cmd.workflow.Relabel(base, phase)
+	utils.Console.CheckError(err)
+	targetChildren := make([]string, 0)
+	for _, child := range children {
+		target, err := cmd.workflow.Relabel(child, phase)
+		utils.Console.CheckError(err)
+		targetChildren = append(targetChildren, target)
+	}
+
+	for _, label := range append([]string{targetBase}, targetChildren...) {
+		if !cmd.channels.Exists(label) {
+			utils.Console.ExitOnStderr(fmt.Sprintf("Channel \"%s\" does not exist in phase \"%s\"", label, phase))
+		}
+	}
+
+	fmt.Printf("System %d: %s -> %s\n", sid, base, targetBase)
+	cmd.SetChannels(sid, targetBase, targetChildren)
+}
+
+// Set flags from CLI and configuration about current runtime session
+func (cmd *systemsCmd) SetCurrentConfig() *systemsCmd {
+	var err error
+	cmd.workflow, err = utils.NewWorkflow(cmd.ctx, cmd.ctx.String("workflow"))
+	utils.Console.CheckError(err)
+	utils.Log.Debug("Configuration set")
+
+	return cmd
+}
+
+// Entry action for the systems sub-app
+func MainSystemsCmd(ctx *cli.Context) error {
+	cmd := NewSystemsCmd(ctx).SetCurrentConfig()
+	utils.Connect(utils.Configuration.GetServerConfig(ctx))
+
+	if ctx.String("system") == "" && ctx.String("group") == "" {
+		utils.Console.ExitOnUnknown("System or system group required.")
+	}
+	systems := cmd.TargetSystems()
+	if len(systems) == 0 {
+		utils.Console.ExitOnStderr("No systems has been found")
+	}
+
+	if ctx.String("phase") != "" {
+		for _, sid := range systems {
+			cmd.MoveToPhase(sid, ctx.String("phase"))
+		}
+	} else if ctx.String("base-channel") != "" || ctx.IsSet("child-channels") {
+		var children []string
+		if ctx.IsSet("child-channels") {
+			children = utils.SplitList(ctx.String("child-channels"))
+		}
+		for _, sid := range systems {
+			cmd.SetChannels(sid, ctx.String("base-channel"), children)
+		}
+	} else {
+		utils.Console.ExitOnUnknown("Don't know what to do.")
+	}
+
+	return nil
+}
@@ -3,7 +3,9 @@ package outputters
 import (
 	"fmt"
 	"github.com/aybabtme/rgbterm"
+	"github.com/isbm/go-asciitable"
 	"sort"
+	"strings"
 )
 
 type ansiCLI struct {
@@ -74,3 +76,53 @@ func (cli *ansiCLI) Tree(tree map[string][]string) {
 		}
 	}
 }
+
+// Table outputs rows as a borderless table with the highlighted header
+func (cli *ansiCLI) Table(header []string, rows [][]interface{}) {
+	titles := make([]string, len(header))
+	for idx, title := range header {
+		titles[idx] = rgbterm.FgString(strings.ToUpper(title), 0xff, 0xff, 0xff)
+	}
+	tableDataContainer := asciitable.NewTableData().SetHeader(titles...)
+	for _, row := range rows {
+		cells := make([]interface{}, len(row))
+		for idx, cell := range row {
+			cells[idx] = cli.tableCell(cell)
+		}
+		tableDataContainer.AddRow(cells...)
+	}
+
+	tableStyle := asciitable.NewBorderStyle(asciitable.BORDER_SINGLE_THIN, asciitable.BORDER_SINGLE_THIN).
+		SetBorderVisible(false).
+		SetGridVisible(false).
+		SetHeaderVisible(true).
+		SetHeaderStyle(asciitable.BORDER_SINGLE_THICK).
+		SetTableWidthFull(true)
+
+	table := asciitable.NewSimpleTable(tableDataContainer, tableStyle).
+		SetCellPadding(1).
+		SetTextWrap(true)
+
+	fmt.Println(table.Render())
+	fmt.Println()
+}
+
+// Convert table cell data to its text representation
+func (cli *ansiCLI) tableCell(cell interface{}) string {
+	switch data := cell.(type) {
+	case nil:
+		return rgbterm.FgString("n/a", 0x80, 0x80, 0x80)
+	case string:
+		return data
+	case []string:
+		return strings.Join(data, ", ")
+	case []interface{}:
+		items := make([]string, len(data))
+		for idx, item := range data {
+			items[idx] = cli.tableCell(item)
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprintf("%v", data)
+	}
+}
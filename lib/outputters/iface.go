@@ -1,5 +1,6 @@
 package outputters
 
 type Output interface {
-	Tree()
+	Tree(tree map[string][]string)
+	Table(header []string, rows [][]interface{})
 }
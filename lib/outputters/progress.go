@@ -0,0 +1,70 @@
+package outputters
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Progress bar of a bulk operation on the terminal. Nothing is shown, if STDERR is not a terminal.
+type Progress struct {
+	title string
+	total int
+	done  int
+	width int
+	tty   bool
+	lock  sync.Mutex
+}
+
+// NewProgress constructor
+func NewProgress(title string, total int) *Progress {
+	progress := new(Progress)
+	progress.title = title
+	progress.total = total
+	progress.width = 30
+	if info, err := os.Stderr.Stat(); err == nil {
+		progress.tty = info.Mode()&os.ModeCharDevice != 0
+	}
+	progress.render()
+
+	return progress
+}
+
+// Draw the bar over the current line
+func (progress *Progress) render() {
+	if !progress.tty || progress.total == 0 {
+		return
+	}
+	filled := progress.width * progress.done / progress.total
+	fmt.Fprintf(os.Stderr, "\r%s [%s%s] %3d%% (%d/%d)", progress.title, strings.Repeat("#", filled),
+		strings.Repeat(".", progress.width-filled), 100*progress.done/progress.total, progress.done, progress.total)
+}
+
+// Add completed items
+func (progress *Progress) Add(count int) {
+	if progress == nil {
+		return
+	}
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	progress.done += count
+	if progress.done > progress.total {
+		progress.done = progress.total
+	}
+	progress.render()
+}
+
+// Finish the bar and move to the next line
+func (progress *Progress) Finish() {
+	if progress == nil {
+		return
+	}
+	progress.lock.Lock()
+	defer progress.lock.Unlock()
+
+	if progress.tty && progress.total > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/*
+AuditEntry is a record of the operation, which changed channels or subscriptions on the server:
+who did it, where, in which workflow, from what to what, how much was changed and how it ended.
+
+Entries are appended as JSON lines to the audit log, "~/.local/share/spaceman/audit.log" by default
+or the file given by --audit-log. The file is never rewritten, only appended.
+*/
+type AuditEntry struct {
+	Time      time.Time      `json:"time"`
+	Operator  string         `json:"operator"`
+	User      string         `json:"user"`
+	Server    string         `json:"server"`
+	Workflow  string         `json:"workflow,omitempty"`
+	Operation string         `json:"operation"`
+	Source    string         `json:"source,omitempty"`
+	Target    string         `json:"target"`
+	Counts    map[string]int `json:"counts,omitempty"`
+	Result    string         `json:"result"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// Results of the audited operations
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Audit log of the local machine
+type auditLog struct {
+	filename string
+	server   string
+	user     string
+	lock     sync.Mutex
+}
+
+// SetFile changes the audit log file. Empty name keeps the default.
+func (audit *auditLog) SetFile(filename string) {
+	if filename != "" {
+		audit.filename = Configuration.ExpandPath(filename)
+	}
+}
+
+// Remember the server and user, whose operations are recorded
+func (audit *auditLog) connected(server string, user string) {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+	audit.server, audit.user = server, user
+}
+
+// Record appends the entry with the result of the operation. Time, operator, and the connected server and user
+// are filled in, unless they are set. Failure to write the audit log is reported, but does not stop the command.
+func (audit *auditLog) Record(entry AuditEntry, err error) {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	entry.Time = time.Now()
+	if current, userErr := user.Current(); userErr == nil {
+		entry.Operator = current.Username
+	}
+	if entry.Server == "" {
+		entry.Server, entry.User = audit.server, audit.user
+	}
+	entry.Result = AuditSuccess
+	if err != nil {
+		entry.Result, entry.Error = AuditFailure, err.Error()
+	}
+
+	if writeErr := audit.append(entry); writeErr != nil {
+		Log.Error("Unable to write audit log \"%s\": %s", audit.filename, writeErr.Error())
+	}
+}
+
+// Append the entry as a line of the audit log
+func (audit *auditLog) append(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(audit.filename), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(audit.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Entries returns all recorded entries in the order of recording
+func (audit *auditLog) Entries() ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0)
+	file, err := os.Open(audit.filename)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("Audit log \"%s\" is broken at line %d: %s", audit.filename, line, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+var Audit *auditLog
+
+func init() {
+	Audit = &auditLog{filename: Configuration.ExpandPath("~/.local/share/spaceman/audit.log")}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Backend performs the Uyuni API calls for all commands: XML-RPC server or an alternative implementation
+type Backend interface {
+	// Call the API function. Session is passed automatically, where it is required.
+	RequestFuction(name string, args ...interface{}) (interface{}, error)
+	// Login and keep the session for the next calls
+	Login() error
+	// Logout and forget the session
+	Logout() error
+	// URL of the server
+	GetURL() string
+}
+
+// BackendFactory creates backend of the named server from its configuration
+type BackendFactory func(name string, serverConfig map[interface{}]interface{}) (Backend, error)
+
+// Backends by the names, used in "backend" option of the server configuration
+var backends = make(map[string]BackendFactory)
+
+// Backend, used when the server configuration has no "backend" option
+const defaultBackend = "xmlrpc"
+
+// RegisterBackend makes the backend available by the name
+func RegisterBackend(name string, factory BackendFactory) {
+	backends[name] = factory
+}
+
+// NewBackend creates backend of the named server, selected by its "backend" option
+func NewBackend(name string, serverConfig map[interface{}]interface{}) Backend {
+	kind := stringOption(serverConfig, "backend")
+	if kind == "" {
+		kind = defaultBackend
+	}
+	factory, exist := backends[kind]
+	if !exist {
+		names := make([]string, 0, len(backends))
+		for backendName := range backends {
+			names = append(names, backendName)
+		}
+		sort.Strings(names)
+		Console.ExitOnStderr(fmt.Sprintf("Unknown backend \"%s\", should be one of %s", kind, strings.Join(names, ", ")))
+	}
+	backend, err := factory(name, serverConfig)
+	Console.CheckError(err)
+
+	return backend
+}
+
+// Backend of all commands. It delegates to the backend of the selected server, so clients created before
+// connecting use it as well.
+type connectedBackend struct {
+	backend Backend
+}
+
+func (connected *connectedBackend) RequestFuction(name string, args ...interface{}) (interface{}, error) {
+	return connected.backend.RequestFuction(name, args...)
+}
+
+func (connected *connectedBackend) Login() error {
+	return connected.backend.Login()
+}
+
+func (connected *connectedBackend) Logout() error {
+	return connected.backend.Logout()
+}
+
+func (connected *connectedBackend) GetURL() string {
+	return connected.backend.GetURL()
+}
+
+// Connect the global backend to the named server
+func Connect(name string, serverConfig map[interface{}]interface{}) {
+	RPC.backend = NewBackend(name, serverConfig)
+	Audit.connected(RPC.backend.GetURL(), stringOption(serverConfig, "user"))
+}
+
+var RPC *connectedBackend
+
+func init() {
+	RPC = &connectedBackend{backend: RPCClient()}
+
+	RegisterBackend(defaultBackend, func(name string, serverConfig map[interface{}]interface{}) (Backend, error) {
+		return RPCClient().Connect(name, serverConfig), nil
+	})
+}
@@ -3,11 +3,13 @@ package utils
 import (
 	"fmt"
 	"github.com/smallfish/simpleyaml"
+	"github.com/thoas/go-funk"
 	"github.com/urfave/cli"
 	"io/ioutil"
-	"log"
 	"os/user"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -15,25 +17,29 @@ import (
 	configFiles allows to keep track of existing configurations
 */
 type configFiles struct {
-	global  string
-	local   string
-	session string
-	used    string
+	global   string
+	local    string
+	sessions string
+	cache    string
+	used     string
+
+	passwordFiles []string
 }
 
 // Config object constructor
 func NewConfig() *configFiles {
 	cfg := new(configFiles)
 	cfg.global = "/etc/rhn/spaceman.conf"
-	cfg.local = cfg.expandPath("~/.config/spaceman/config.conf")
-	cfg.session = cfg.expandPath("~/.config/spaceman/session.conf")
+	cfg.local = cfg.ExpandPath("~/.config/spaceman/config.conf")
+	cfg.sessions = cfg.ExpandPath("~/.config/spaceman/sessions")
+	cfg.cache = cfg.ExpandPath("~/.cache/spaceman")
 	cfg.used = cfg.local
 
 	return cfg
 }
 
 // Expands "~" to "$HOME".
-func (cfg *configFiles) expandPath(path string) string {
+func (cfg *configFiles) ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		usr, _ := user.Current()
 		path = filepath.Join(usr.HomeDir, path[2:])
@@ -56,48 +62,117 @@ func (cfg *configFiles) GetConfigFile(ctx *cli.Context) string {
 	return cfg.used
 }
 
-// Returns path of the session config
-func (cfg *configFiles) GetSessionConfFilePath() string {
-	return cfg.session
+// Returns file name safe key of the server URL and user
+func (cfg *configFiles) serverKey(url string, user string) string {
+	key := sessionKeyPattern.ReplaceAllString(user+"@"+regexp.MustCompile(`^\w+://`).ReplaceAllString(url, ""), "_")
+	return strings.Trim(key, "_")
+}
+
+// Returns path of the session config for the server URL and user
+func (cfg *configFiles) GetSessionConfFilePath(url string, user string) string {
+	return filepath.Join(cfg.sessions, "session-"+cfg.serverKey(url, user)+".conf")
+}
+
+// Returns directory of the cached responses for the server URL and user
+func (cfg *configFiles) GetCacheDirPath(url string, user string) string {
+	return filepath.Join(cfg.cache, cfg.serverKey(url, user))
 }
 
 func (cfg *configFiles) checkFail(err error, message string) {
 	if err != nil {
-		log.Fatal(err)
+		Log.Fatal(err.Error())
 		panic(message)
 	}
 }
 
+// ReadYaml reads and parses any YAML file
+func (cfg *configFiles) ReadYaml(filename string) (*simpleyaml.Yaml, error) {
+	source, err := ioutil.ReadFile(cfg.ExpandPath(filename))
+	if err != nil {
+		return nil, err
+	}
+	return simpleyaml.NewYaml(source)
+}
+
+// Read and merge the effective configuration
+func (cfg *configFiles) load(ctx *cli.Context) map[interface{}]interface{} {
+	if cfg.GetConfigFile(ctx) == "" {
+		panic("Unable to obtain configuration")
+	}
+	return cfg.merge(ctx).data
+}
+
 func (cfg *configFiles) GetConfig(ctx *cli.Context, sections ...string) *map[string]interface{} {
-	filename := cfg.GetConfigFile(ctx)
-	if filename != "" {
-		filename = cfg.expandPath(filename)
-		source, err := ioutil.ReadFile(filename)
-		cfg.checkFail(err, "Unable to read configuration file")
-
-		data, err := simpleyaml.NewYaml(source)
-		cfg.checkFail(err, "Unable to parse YAML data")
-
-		content := make(map[string]interface{})
-		globalConfig, err := data.Map()
-		cfg.checkFail(err, "Configuration syntax error: structure expected")
-		for _, section := range sections {
-			sectionConfig, exist := globalConfig[section]
-			if exist {
-				content[section] = sectionConfig
-			} else {
-				log.Printf("Section '%s' does not exist", section)
-			}
+	globalConfig := cfg.load(ctx)
+	content := make(map[string]interface{})
+	for _, section := range sections {
+		sectionConfig, exist := globalConfig[section]
+		if exist {
+			content[section] = sectionConfig
+		} else {
+			Log.Warning("Section '%s' does not exist", section)
+		}
+	}
+	if len(content) == 0 {
+		Log.Fatal("No configuration found for %s sections", strings.Join(sections, ", "))
+	}
+
+	return &content
+}
+
+// Returns configuration section, if it is present. Unlike GetConfig, missing section is not an error.
+func (cfg *configFiles) LookupSection(ctx *cli.Context, section string) (interface{}, bool) {
+	sectionConfig, exist := cfg.load(ctx)[section]
+	return sectionConfig, exist && sectionConfig != nil
+}
+
+/*
+GetServerConfig returns name and configuration of the server.
+
+Server is selected by --server option (or SPACEMAN_SERVER environment variable)
+from the "servers" section. Without selection, unnamed "server" section is used,
+or the only one named server, or the one named "default".
+*/
+func (cfg *configFiles) GetServerConfig(ctx *cli.Context) (string, map[interface{}]interface{}) {
+	return cfg.GetNamedServerConfig(ctx, ctx.GlobalString("server"))
+}
+
+// GetNamedServerConfig returns name and configuration of the server by its name. See GetServerConfig.
+func (cfg *configFiles) GetNamedServerConfig(ctx *cli.Context, name string) (string, map[interface{}]interface{}) {
+	globalConfig := cfg.load(ctx)
+	servers, _ := globalConfig["servers"].(map[interface{}]interface{})
+
+	if name == "" {
+		if serverConfig, exist := globalConfig["server"].(map[interface{}]interface{}); exist {
+			return "", serverConfig
+		}
+		names := make([]string, 0)
+		for serverName := range servers {
+			names = append(names, fmt.Sprint(serverName))
 		}
-		if len(content) == 0 {
-			log.Fatal(fmt.Sprintf("No configuration found for %s sections", strings.Join(sections, ", ")))
+		sort.Strings(names)
+		if len(names) == 1 {
+			name = names[0]
+		} else if funk.ContainsString(names, "default") {
+			name = "default"
+		} else if len(names) == 0 {
+			Console.ExitOnStderr("Server configuration section is missing.")
+		} else {
+			Console.ExitOnUnknown(fmt.Sprintf("Several servers are configured (%s), please select one.", strings.Join(names, ", ")))
 		}
+	}
 
-		return &content
+	serverConfig, exist := servers[name].(map[interface{}]interface{})
+	if !exist {
+		Console.ExitOnStderr(fmt.Sprintf("Server \"%s\" is not configured.", name))
 	}
-	panic("Unable to obtain configuration")
+
+	return name, serverConfig
 }
 
+// Characters, not allowed in session file names
+var sessionKeyPattern = regexp.MustCompile(`[^\w.@-]+`)
+
 var Configuration configFiles
 
 func init() {
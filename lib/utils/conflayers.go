@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+/*
+Effective configuration is merged from the layers, later ones override options of earlier ones:
+
+	1. global file:        /etc/rhn/spaceman.conf
+	2. user file:          ~/.config/spaceman/config.conf or the file given by --config
+	3. project-local file: .spaceman.conf in the current directory or the nearest of its parents
+	4. environment:        SPACEMAN_<SECTION>__<OPTION>, e.g. SPACEMAN_SERVER__USER=admin
+	                       or SPACEMAN_SERVERS__PROD__URL=https://prod/rpc/api
+
+Sections are merged option by option, other values (including lists) are replaced.
+Names from environment variables are lower case, values are parsed as YAML, so "false" or "300" are not strings.
+Every file is optional, but at least one layer should be present. File given by --config must exist.
+Project-local file may run commands (password_cmd), so it must be owned by the current user and not writable by others.
+*/
+
+// Name of the project-local configuration file
+const projectConfigName = ".spaceman.conf"
+
+// Prefix of environment variables with configuration options and separator of their sections
+const (
+	configEnvPrefix    = "SPACEMAN_"
+	configEnvSeparator = "__"
+)
+
+// ConfigOption is an option of the effective configuration and the file or environment variable it comes from
+type ConfigOption struct {
+	Path   string
+	Value  interface{}
+	Source string
+}
+
+// Configuration merged from all layers
+type mergedConfig struct {
+	data          map[interface{}]interface{}
+	sources       map[string]string
+	layers        []string
+	passwordFiles []string
+}
+
+// Find project-local configuration file in the current directory or its parents
+func (cfg *configFiles) projectFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Check that the project-local file is owned by the current user and is not writable by group or others
+func checkProjectFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("Project configuration \"%s\" is not owned by the current user", path)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("Project configuration \"%s\" must not be writable by group or others (chmod go-w)", path)
+	}
+	return nil
+}
+
+// Read the configuration file of the layer. Missing file is skipped, unless it is required.
+func (cfg *configFiles) readLayer(filename string, required bool) (map[interface{}]interface{}, bool) {
+	filename = cfg.ExpandPath(filename)
+	if _, err := os.Stat(filename); os.IsNotExist(err) && !required {
+		return nil, false
+	}
+	data, err := cfg.ReadYaml(filename)
+	if err != nil {
+		cfg.checkFail(fmt.Errorf("Unable to read configuration file \"%s\": %s", filename, err.Error()), "Unable to read configuration file")
+	}
+	layer, err := data.Map()
+	if err != nil {
+		cfg.checkFail(fmt.Errorf("Configuration file \"%s\": structure expected", filename), "Configuration syntax error: structure expected")
+	}
+	return layer, true
+}
+
+// Get options from SPACEMAN_<SECTION>__<OPTION> environment variables, by variable names
+func envLayers() map[string]map[interface{}]interface{} {
+	layers := make(map[string]map[interface{}]interface{})
+	for _, variable := range os.Environ() {
+		pair := strings.SplitN(variable, "=", 2)
+		name := pair[0]
+		if len(pair) != 2 || !strings.HasPrefix(name, configEnvPrefix) || !strings.Contains(name, configEnvSeparator) {
+			continue
+		}
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(pair[1]), &value); err != nil || value == nil {
+			value = pair[1]
+		}
+
+		keys := strings.Split(strings.ToLower(strings.TrimPrefix(name, configEnvPrefix)), configEnvSeparator)
+		layer := make(map[interface{}]interface{})
+		section := layer
+		for _, key := range keys[:len(keys)-1] {
+			nested := make(map[interface{}]interface{})
+			section[key] = nested
+			section = nested
+		}
+		section[keys[len(keys)-1]] = value
+		layers[name] = layer
+	}
+	return layers
+}
+
+// Merge options of the layer into the configuration, remembering their source
+func mergeLayer(config map[interface{}]interface{}, layer map[interface{}]interface{}, prefix string, source string,
+	sources map[string]string) {
+	for key, value := range layer {
+		path := prefix + fmt.Sprint(key)
+		section, isSection := value.(map[interface{}]interface{})
+		current, hasSection := config[key].(map[interface{}]interface{})
+		if isSection && hasSection {
+			mergeLayer(current, section, path+".", source, sources)
+			continue
+		}
+
+		for option := range sources {
+			if strings.HasPrefix(option, path+".") {
+				delete(sources, option)
+			}
+		}
+		config[key] = value
+		sources[path] = source
+		if isSection {
+			for option := range flattenConfig(section, path+".") {
+				sources[option] = source
+			}
+		}
+	}
+}
+
+// Flatten the configuration to the options by their dotted paths, e.g. "server.user"
+func flattenConfig(config map[interface{}]interface{}, prefix string) map[string]interface{} {
+	options := make(map[string]interface{})
+	for key, value := range config {
+		path := prefix + fmt.Sprint(key)
+		if section, ok := value.(map[interface{}]interface{}); ok && len(section) > 0 {
+			for option, optionValue := range flattenConfig(section, path+".") {
+				options[option] = optionValue
+			}
+		} else {
+			options[path] = value
+		}
+	}
+	return options
+}
+
+// Check, if any section of the configuration has a password
+func hasPassword(config map[interface{}]interface{}) bool {
+	for key, value := range config {
+		if key == "password" {
+			return true
+		}
+		if section, ok := value.(map[interface{}]interface{}); ok && hasPassword(section) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge configuration from all layers
+func (cfg *configFiles) merge(ctx *cli.Context) *mergedConfig {
+	merged := &mergedConfig{
+		data:          make(map[interface{}]interface{}),
+		sources:       make(map[string]string),
+		layers:        make([]string, 0),
+		passwordFiles: make([]string, 0),
+	}
+
+	files := []string{cfg.global, cfg.GetConfigFile(ctx)}
+	if project := cfg.projectFile(); project != "" {
+		cfg.checkFail(checkProjectFile(project), "Untrusted project configuration")
+		files = append(files, project)
+	}
+	for idx, filename := range files {
+		layer, exist := cfg.readLayer(filename, idx == 1 && ctx.GlobalIsSet("config"))
+		if !exist {
+			continue
+		}
+		filename = cfg.ExpandPath(filename)
+		mergeLayer(merged.data, layer, "", filename, merged.sources)
+		merged.layers = append(merged.layers, filename)
+		if hasPassword(layer) {
+			merged.passwordFiles = append(merged.passwordFiles, filename)
+		}
+	}
+
+	env := envLayers()
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mergeLayer(merged.data, env[name], "", name, merged.sources)
+		merged.layers = append(merged.layers, name)
+	}
+
+	if len(merged.layers) == 0 {
+		Console.ExitOnStderr(fmt.Sprintf("No configuration found, please create \"%s\"", cfg.GetConfigFile(ctx)))
+	}
+	cfg.passwordFiles = merged.passwordFiles
+
+	return merged
+}
+
+// Options returns all options of the effective configuration with their sources, sorted by paths
+func (cfg *configFiles) Options(ctx *cli.Context) []ConfigOption {
+	merged := cfg.merge(ctx)
+	options := make([]ConfigOption, 0)
+	for path, value := range flattenConfig(merged.data, "") {
+		options = append(options, ConfigOption{Path: path, Value: value, Source: merged.sources[path]})
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Path < options[j].Path
+	})
+	return options
+}
+
+// Sources returns files and environment variables of the effective configuration, from the lowest precedence
+func (cfg *configFiles) Sources(ctx *cli.Context) []string {
+	return cfg.merge(ctx).layers
+}
+
+// PasswordFiles returns configuration files of the last loaded configuration, which contain a password
+func (cfg *configFiles) PasswordFiles() []string {
+	return cfg.passwordFiles
+}
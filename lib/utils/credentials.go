@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/smallfish/simpleyaml"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+/*
+credentials resolve password of the server user from the configured source:
+
+	password:         plain text password, only from files, not readable by others
+	password_cmd:     command, which prints the password to STDOUT, e.g. "pass show uyuni"
+	password_env:     environment variable with the password (SPACEMAN_PASSWORD by default)
+	credentials_file: file with 0600 permissions, containing "password: ..." or just a password
+
+If nothing is configured, password is prompted interactively without echo.
+*/
+type credentials struct {
+	url    string
+	user   string
+	config map[interface{}]interface{}
+}
+
+// NewCredentials constructor
+func NewCredentials(url string, user string, serverConfig map[interface{}]interface{}) *credentials {
+	creds := new(credentials)
+	creds.url = url
+	creds.user = user
+	creds.config = serverConfig
+	return creds
+}
+
+// Get string option of the server configuration
+func (creds *credentials) option(name string) string {
+	value, _ := creds.config[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// Check that the file permissions have none of the mask bits
+func (creds *credentials) checkPrivate(path string, mask os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&mask != 0 {
+		return fmt.Errorf("File \"%s\" contains a password and must not be readable by others (chmod 0600)", path)
+	}
+	return nil
+}
+
+// Run password command and return the first line of its output
+func (creds *credentials) fromCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Password command failed: %s", err.Error())
+	}
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
+
+// Read password from the private credentials file
+func (creds *credentials) fromFile(path string) (string, error) {
+	path = Configuration.ExpandPath(path)
+	if err := creds.checkPrivate(path, 0077); err != nil {
+		return "", err
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if data, err := simpleyaml.NewYaml(source); err == nil {
+		if password, err := data.Get("password").String(); err == nil {
+			return password, nil
+		}
+	}
+	return strings.TrimSpace(string(source)), nil
+}
+
+// Prompt password on the terminal without echo
+func (creds *credentials) prompt() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return "", errors.New("Password is not configured and cannot be prompted without a terminal")
+	}
+
+	echo := func(enabled bool) error {
+		mode := "-echo"
+		if enabled {
+			mode = "echo"
+		}
+		stty := exec.Command("stty", mode)
+		stty.Stdin = os.Stdin
+		return stty.Run()
+	}
+
+	// Password should never be shown, so it is not prompted, if echo cannot be turned off
+	if err := echo(false); err != nil {
+		return "", fmt.Errorf("Password is not configured and cannot be prompted without echo: %s", err.Error())
+	}
+	defer echo(true)
+	fmt.Fprintf(os.Stderr, "Password for %s at %s: ", creds.user, creds.url)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// Check refuses configuration files, which contain a password and are readable by everyone
+func (creds *credentials) Check() error {
+	if _, exist := creds.config["password"]; exist {
+		for _, filename := range Configuration.PasswordFiles() {
+			if err := creds.checkPrivate(filename, 0004); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Password of the user from the first configured source
+func (creds *credentials) Password() (string, error) {
+	if password, exist := creds.config["password"].(string); exist {
+		return password, nil
+	}
+	if command := creds.option("password_cmd"); command != "" {
+		return creds.fromCommand(command)
+	}
+	envVar := creds.option("password_env")
+	if envVar == "" {
+		envVar = "SPACEMAN_PASSWORD"
+	}
+	if password, exist := os.LookupEnv(envVar); exist {
+		return password, nil
+	}
+	if path := creds.option("credentials_file"); path != "" {
+		return creds.fromFile(path)
+	}
+	return creds.prompt()
+}
@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -14,11 +19,50 @@ const (
 	_fatal
 )
 
+// Names of the logging levels
+var levelNames = map[int]string{
+	_info:    "INFO",
+	_error:   "ERROR",
+	_warning: "WARNING",
+	_debug:   "DEBUG",
+	_fatal:   "FATAL",
+}
+
+/*
+LoggerController writes log messages of all commands to STDERR or the file given by --log-file.
+Messages have a timestamp, level and optional fields, like channel, phase, operation or duration:
+
+	text: 2020-05-01 12:00:00 [INFO] Merging packages channel=dev-sles operation=merge
+	json: {"channel":"dev-sles","level":"INFO","message":"Merging packages","operation":"merge","time":"2020-05-01T12:00:00Z"}
+*/
 type LoggerController struct {
 	errors   bool
 	warnings bool
 	infos    bool
 	debugs   bool
+	format   string
+	fields   []logField
+	buffer   *bytes.Buffer
+	sink     *logSink
+}
+
+// Field of the log message
+type logField struct {
+	key   string
+	value interface{}
+}
+
+// Destination of the log messages, shared by all copies of the logger
+type logSink struct {
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+// Write whole messages, so they are not interleaved
+func (sink *logSink) write(data []byte) {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	sink.writer.Write(data)
 }
 
 func NewLoggerController(nfo bool, warn bool, err bool, dbg bool) *LoggerController {
@@ -27,71 +71,156 @@ func NewLoggerController(nfo bool, warn bool, err bool, dbg bool) *LoggerControl
 	controller.infos = nfo
 	controller.warnings = warn
 	controller.debugs = dbg
+	controller.format = "text"
+	controller.sink = &logSink{writer: os.Stderr}
 
 	return controller
 }
 
-// Put an information to the logger
-func (logger *LoggerController) put(level int, message string, args ...string) {
-	var prefix string
-	switch level {
-	case _info:
-		prefix = "INFO"
-	case _warning:
-		prefix = "WARNING"
-	case _error:
-		prefix = "ERROR"
-	case _debug:
-		prefix = "DEBUG"
-	case _fatal:
-		prefix = "FATAL"
+// Configure levels, format and destination of the logger from the global options
+func (logger *LoggerController) Configure(verbose bool, quiet bool, format string, filename string) error {
+	logger.infos, logger.warnings, logger.debugs = verbose, verbose, verbose
+	logger.errors = !quiet
+
+	switch format {
+	case "":
+	case "text", "json":
+		logger.format = format
 	default:
-		Console.ExitOnStderr(fmt.Sprintf("Unknown logging level: %d", level))
+		return fmt.Errorf("Unknown log format \"%s\", should be \"text\" or \"json\"", format)
 	}
 
-	if len(args) > 0 {
-		sargs := make([]interface{}, len(args))
-		for idx := range sargs {
-			sargs[idx] = args[idx]
+	if filename != "" {
+		file, err := os.OpenFile(Configuration.ExpandPath(filename), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return fmt.Errorf("Unable to open log file: %s", err.Error())
 		}
-		message = fmt.Sprintf(message, sargs...)
+		logger.sink.writer = file
 	}
 
-	log.Printf("[%s] %s\n", prefix, message)
+	return nil
+}
+
+// With returns a copy of the logger, which adds the field to every message
+func (logger *LoggerController) With(key string, value interface{}) *LoggerController {
+	copied := *logger
+	copied.fields = append(append([]logField{}, logger.fields...), logField{key: key, value: value})
+	return &copied
+}
+
+// Format the field value for the text log
+func fieldText(value interface{}) string {
+	text := fmt.Sprint(value)
+	if strings.ContainsAny(text, " \t\"=") {
+		return fmt.Sprintf("%q", text)
+	}
+	return text
+}
+
+// Format the message with its timestamp and fields
+func (logger *LoggerController) render(level int, message string) []byte {
+	now := time.Now()
+	if logger.format == "json" {
+		entry := map[string]interface{}{
+			"time":    now.Format(time.RFC3339),
+			"level":   levelNames[level],
+			"message": message,
+		}
+		for _, field := range logger.fields {
+			if duration, ok := field.value.(time.Duration); ok {
+				entry[field.key] = duration.String()
+			} else {
+				entry[field.key] = field.value
+			}
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"time": now.Format(time.RFC3339), "level": levelNames[level], "message": message})
+		}
+		return append(data, '\n')
+	}
+
+	line := fmt.Sprintf("%s [%s] %s", now.Format("2006-01-02 15:04:05"), levelNames[level], message)
+	for _, field := range logger.fields {
+		line += fmt.Sprintf(" %s=%s", field.key, fieldText(field.value))
+	}
+	return []byte(line + "\n")
+}
+
+// Put an information to the logger
+func (logger *LoggerController) put(level int, message string, args ...interface{}) {
+	if _, exist := levelNames[level]; !exist {
+		Console.ExitOnStderr(fmt.Sprintf("Unknown logging level: %d", level))
+	}
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	message = strings.TrimRight(message, "\n")
+
+	if logger.buffer != nil {
+		logger.buffer.Write(logger.render(level, message))
+	} else {
+		logger.sink.write(logger.render(level, message))
+	}
 	if level == _fatal {
+		logger.Flush()
 		os.Exit(1)
 	}
 }
 
 // Log info level
-func (logger *LoggerController) Info(message string, args ...string) {
+func (logger *LoggerController) Info(message string, args ...interface{}) {
 	if logger.infos {
 		logger.put(_info, message, args...)
 	}
 }
 
 // Log warning level
-func (logger *LoggerController) Warning(message string, args ...string) {
+func (logger *LoggerController) Warning(message string, args ...interface{}) {
 	if logger.warnings {
 		logger.put(_warning, message, args...)
 	}
 }
 
 // Log error level
-func (logger *LoggerController) Error(message string, args ...string) {
+func (logger *LoggerController) Error(message string, args ...interface{}) {
 	if logger.errors {
 		logger.put(_error, message, args...)
 	}
 }
 
 // Log debug level
-func (logger *LoggerController) Debug(message string, args ...string) {
+func (logger *LoggerController) Debug(message string, args ...interface{}) {
 	if logger.debugs {
 		logger.put(_debug, message, args...)
 	}
 }
 
 // Log fatal message and quit
-func (logger *LoggerController) Fatal(message string, args ...string) {
+func (logger *LoggerController) Fatal(message string, args ...interface{}) {
 	logger.put(_fatal, message, args...)
 }
+
+// Buffered returns a copy of the logger with the same levels and fields, which keeps messages until flushed.
+// This is used by concurrent workers, so the output of each of them goes together.
+func (logger *LoggerController) Buffered() *LoggerController {
+	buffered := *logger
+	buffered.buffer = new(bytes.Buffer)
+	return &buffered
+}
+
+// Flush buffered messages to the log
+func (logger *LoggerController) Flush() {
+	if logger.buffer == nil {
+		return
+	}
+	logger.sink.write(logger.buffer.Bytes())
+	logger.buffer.Reset()
+}
+
+// Log is the logger, shared by all commands
+var Log *LoggerController
+
+func init() {
+	Log = NewLoggerController(false, false, true, false)
+}
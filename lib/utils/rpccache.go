@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Responses of the read-only calls are cached on disk per server URL and user, configured in the server section:
+
+	cache:      true   set to false to always call the server
+	cache_ttl:  5m     how long a cached response is used
+	cache_ttls:        time to live of specific methods
+	  channel.software.listAllPackages: 1h
+
+Any call, which changes something on the server, drops the whole cache of the server.
+Lists also change without any call of the client, e.g. when repositories are synchronised. So commands,
+which change channels by the lists they read (lifecycle, import, apply), always read from the server.
+*/
+type rpcCache struct {
+	directory string
+	ttl       time.Duration
+	ttls      map[string]time.Duration
+	refresh   bool
+	lock      sync.Mutex
+}
+
+// Cached response of a call
+type cacheEntry struct {
+	Method string
+	Stored time.Time
+	Result interface{}
+}
+
+// Read-only methods, whose responses are cached. Channel details are not cached: they are polled
+// for the last synchronisation time, which changes on the server without any call of the client.
+var cachedMethods = []string{
+	"channel.listSoftwareChannels",
+	"channel.listAllChannels",
+	"channel.software.listAllPackages",
+	"channel.software.listErrata",
+	"channel.software.listChildren",
+	"channel.software.listChannelRepos",
+	"channel.software.listUserRepos",
+}
+
+// Method names, starting with these verbs, change data on the server
+var writeVerbs = []string{"add", "align", "associate", "clone", "create", "delete", "disassociate", "merge",
+	"publish", "regenerate", "remove", "rename", "schedule", "set", "subscribe", "sync", "unsubscribe", "update"}
+
+// Command line choice for the cache: disabled entirely, or refreshed from the server
+var cacheDisabled, cacheRefresh bool
+
+// SetCacheMode turns the cache off or forces to refresh cached responses for all clients, connected afterwards
+func SetCacheMode(disabled bool, refresh bool) {
+	cacheDisabled, cacheRefresh = disabled, refresh
+}
+
+// RefreshCache makes clients, connected afterwards, read from the server and only store the responses in the cache
+func RefreshCache() {
+	cacheRefresh = true
+}
+
+func init() {
+	// Types of the XML-RPC values, which are stored as interfaces
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
+// Create cache of the server from its configuration. Returns nil, if the cache is off.
+func newRPCCache(url string, user string, serverConfig map[interface{}]interface{}) (*rpcCache, error) {
+	if enabled, ok := serverConfig["cache"].(bool); cacheDisabled || (ok && !enabled) {
+		return nil, nil
+	}
+
+	cache := &rpcCache{
+		directory: Configuration.GetCacheDirPath(url, user),
+		ttls:      make(map[string]time.Duration),
+		refresh:   cacheRefresh,
+	}
+
+	var err error
+	if cache.ttl, err = durationOption(serverConfig, "cache_ttl", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if overrides, exist := serverConfig["cache_ttls"].(map[interface{}]interface{}); exist {
+		for method := range overrides {
+			name := fmt.Sprint(method)
+			if cache.ttls[name], err = durationOption(overrides, name, cache.ttl); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cache, nil
+}
+
+// Tell if the method response is cached
+func (cache *rpcCache) caches(method string) bool {
+	if cache == nil {
+		return false
+	}
+	for _, name := range cachedMethods {
+		if name == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Tell if the method changes data on the server
+func isWriteMethod(method string) bool {
+	verb := method[strings.LastIndex(method, ".")+1:]
+	for _, prefix := range writeVerbs {
+		if strings.HasPrefix(verb, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get file of the cached call. Session token is not a part of the key.
+func (cache *rpcCache) entryFile(method string, args []interface{}) string {
+	digest := sha1.Sum([]byte(fmt.Sprintf("%s%#v", method, args)))
+	return filepath.Join(cache.directory, method+"-"+hex.EncodeToString(digest[:8])+".gob")
+}
+
+// Get time to live of the method responses
+func (cache *rpcCache) ttlOf(method string) time.Duration {
+	if ttl, exist := cache.ttls[method]; exist {
+		return ttl
+	}
+	return cache.ttl
+}
+
+// Get cached response of the call, if it is still fresh. Broken entries are treated as missing.
+func (cache *rpcCache) Get(method string, args []interface{}) (interface{}, bool) {
+	if !cache.caches(method) || cache.refresh {
+		return nil, false
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	file, err := os.Open(cache.entryFile(method, args))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	entry := new(cacheEntry)
+	if err := gob.NewDecoder(file).Decode(entry); err != nil || entry.Method != method {
+		return nil, false
+	}
+	if time.Since(entry.Stored) > cache.ttlOf(method) {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Store response of the call. Cache is only an optimisation, so failures to write it are ignored.
+func (cache *rpcCache) Put(method string, args []interface{}, result interface{}) {
+	if !cache.caches(method) {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if err := os.MkdirAll(cache.directory, 0700); err != nil {
+		return
+	}
+	filename := cache.entryFile(method, args)
+	file, err := os.OpenFile(filename+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	err = gob.NewEncoder(file).Encode(&cacheEntry{Method: method, Stored: time.Now(), Result: result})
+	file.Close()
+	if err == nil {
+		err = os.Rename(filename+".tmp", filename)
+	}
+	if err != nil {
+		os.Remove(filename + ".tmp")
+	}
+}
+
+// Drop the whole cache of the server, if the method changes data on it
+func (cache *rpcCache) Invalidate(method string) {
+	if cache == nil || !isWriteMethod(method) {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	os.RemoveAll(cache.directory)
+}
@@ -1,125 +1,341 @@
 package utils
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/kolo/xmlrpc"
+	"github.com/thoas/go-funk"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // RPC client object to call the XML-RPC server
 type rpcClient struct {
-	url        string
-	user       string
-	password   string
-	session    string
-	connection *xmlrpc.Client
-	inUse      bool
+	url         string
+	user        string
+	password    string
+	credentials *credentials
+	session     string
+	server      string
+	policy      *callPolicy
+	cache       *rpcCache
+	recorder    *rpcRecorder
+	connection  *http.Client
+	lock        *sync.Mutex
 }
 
 // RPCClient object constructor
 func RPCClient() *rpcClient {
 	client := new(rpcClient)
-	client.session = client.GetSession()
-	client.inUse = false
+	client.lock = new(sync.Mutex)
 
 	return client
 }
 
-func (client *rpcClient) Connect(config map[string]interface{}) *rpcClient {
-	serverConfig, exist := config["server"].(map[interface{}]interface{})
-	if !exist {
-		Console.CheckError(errors.New("Server configuration section is missing."))
-	}
+// Connect to the named server. Session is kept per server URL and user.
+func (client *rpcClient) Connect(name string, serverConfig map[interface{}]interface{}) *rpcClient {
+	client.server = name
 
 	url, exist := serverConfig["url"].(string)
 	if !exist {
-		Console.CheckError(errors.New("Server URL must be defined in 'server' section."))
+		Console.CheckError(errors.New("Server URL must be defined in server configuration."))
 	}
 	client.url = url
 
 	user, exist := serverConfig["user"].(string)
 	if !exist {
-		Console.CheckError(errors.New("User ID must be specified in 'server' section."))
+		Console.CheckError(errors.New("User ID must be specified in server configuration."))
 	}
 	client.user = user
+	client.credentials = NewCredentials(url, user, serverConfig)
 
-	password, exist := serverConfig["password"].(string)
-	if !exist {
-		Console.CheckError(errors.New("Password should be set in 'server' section."))
+	recorder, err := newRPCRecorder()
+	Console.CheckError(err)
+	client.recorder = recorder
+	if recorder.replaying() {
+		// Recording has neither real session nor password
+		client.session, client.password = redactedValue, redactedValue
+	} else {
+		Console.CheckError(client.credentials.Check())
 	}
-	client.password = password
 
-	insecure, exist := serverConfig["insecure"].(bool)
-	if !exist {
-		insecure = false
+	policy, err := newCallPolicy(serverConfig)
+	Console.CheckError(err)
+	client.policy = policy
+	if recorder == nil {
+		// Recording must contain all the calls, and replay must not mix with real responses
+		client.cache, err = newRPCCache(url, user, serverConfig)
+		Console.CheckError(err)
 	}
 
-	client.connection, _ = xmlrpc.NewClient(client.url,
-		&http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: insecure,
-			},
-		})
+	tlsConfig, err := newTLSConfig(serverConfig)
+	Console.CheckError(err)
+	proxy, err := proxyOption(serverConfig)
+	Console.CheckError(err)
+
+	client.connection = &http.Client{
+		Transport: &http.Transport{
+			Proxy:               proxy,
+			DialContext:         (&net.Dialer{Timeout: policy.connectTimeout, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout: policy.connectTimeout,
+			TLSClientConfig:     tlsConfig,
+		},
+	}
 
 	return client
 }
 
-// Store session into the file
+// Get URL of the connected server
+func (client *rpcClient) GetURL() string {
+	return client.url
+}
+
+// Get path of the session file, which is kept per server URL and user
+func (client *rpcClient) sessionFile() string {
+	return Configuration.GetSessionConfFilePath(client.url, client.user)
+}
+
+// Store session into the file. Replayed session is not stored.
 func (client *rpcClient) storeSession() error {
-	return ioutil.WriteFile(Configuration.GetSessionConfFilePath(), []byte(client.session+"\n"), 0600)
+	if client.recorder.replaying() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(client.sessionFile()), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(client.sessionFile(), []byte(client.session+"\n"), 0600)
 }
 
-// Get stored session
-func (client *rpcClient) GetSession() string {
-	var session string
-	if !fileExists(Configuration.GetSessionConfFilePath()) {
-		session = ""
-		Console.CheckError(errors.New("Session file does not exists"))
-	} else {
-		data, err := ioutil.ReadFile(Configuration.GetSessionConfFilePath())
-		Console.CheckError(err)
-		session = strings.TrimSpace(string(data))
+// Load stored session, if any
+func (client *rpcClient) loadSession() error {
+	if client.session == "" && fileExists(client.sessionFile()) {
+		data, err := ioutil.ReadFile(client.sessionFile())
+		if err != nil {
+			return err
+		}
+		client.session = strings.TrimSpace(string(data))
+	}
+	return nil
+}
+
+// Get current session. Stored session is loaded on the first use, and if there is none, client logs in.
+func (client *rpcClient) GetSession() (string, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.loadSession(); err != nil {
+		return "", err
 	}
+	if client.session == "" {
+		if err := client.login(); err != nil {
+			return "", err
+		}
+	}
+
+	return client.session, nil
+}
+
+// Login to the server, replacing stored session
+func (client *rpcClient) Login() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return client.login()
+}
+
+// Login again, if the session is still the rejected one. Concurrent calls, rejected with
+// the same expired session, login only once and all get the new session.
+func (client *rpcClient) relogin(rejected string) (string, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.session == rejected {
+		if err := client.login(); err != nil {
+			return "", err
+		}
+	}
+	return client.session, nil
+}
+
+// Login to the server. Caller must hold the lock.
+func (client *rpcClient) login() error {
+	if client.password == "" {
+		password, err := client.credentials.Password()
+		if err != nil {
+			return err
+		}
+		client.password = password
+	}
+
+	client.session = ""
+	session, err := AsString(client.call("auth.login", []interface{}{client.user, client.password}))
+	if err != nil {
+		return err
+	}
+	client.session = session
+
+	return client.storeSession()
+}
 
-	return session
+// Logout from the server and remove stored session
+func (client *rpcClient) Logout() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if err := client.loadSession(); err != nil {
+		return err
+	}
+	if client.session != "" {
+		_, err := client.call("auth.logout", []interface{}{client.session})
+		if fault, ok := err.(*FaultError); err != nil && !(ok && fault.IsAuth()) {
+			return err
+		}
+		client.session = ""
+	}
+	if fileExists(client.sessionFile()) && !client.recorder.replaying() {
+		return os.Remove(client.sessionFile())
+	}
+	return nil
 }
 
-func (client *rpcClient) auth() {
-	client.inUse = true
-	client.session = client.RequestFuction("auth.login", client.user, client.password).(string)
-	Console.CheckError(client.storeSession())
+// Post the encoded call to the server within the read timeout of the method and read the response
+func (client *rpcClient) post(name string, body []byte) ([]byte, error) {
+	timeout := client.policy.timeoutOf(name)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, err := http.NewRequest("POST", client.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "text/xml")
+
+	httpResponse, err := client.connection.Do(request.WithContext(ctx))
+	if err == nil {
+		defer httpResponse.Body.Close()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("no response within %s", timeout)
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch httpResponse.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, &transientError{fmt.Errorf("bad HTTP status %s", httpResponse.Status)}
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		return nil, fmt.Errorf("bad HTTP status %s", httpResponse.Status)
+	}
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("no response within %s", timeout)
+	}
+	return data, err
 }
 
-// Request a function call on the remote
-func (client *rpcClient) RequestFuction(name string, args ...interface{}) (v interface{}) {
+// Perform XML-RPC call. Transient transport errors are retried with exponential backoff, see isRetriable.
+func (client *rpcClient) call(name string, args []interface{}) (interface{}, error) {
 	if client.connection == nil {
-		Console.CheckError(errors.New("client is not connected yet"))
+		return nil, errors.New("client is not connected yet")
+	}
+
+	body, err := xmlrpc.EncodeMethodCall(name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if client.recorder.replaying() {
+		data, err = client.recorder.Replay(name, args)
+	} else {
+		for attempt := 0; ; attempt++ {
+			data, err = client.post(name, body)
+			if err == nil || !isRetriable(name, err) || attempt >= client.policy.retries {
+				break
+			}
+			delay := client.policy.delay(attempt)
+			Log.Warning("%s: %s, retrying in %s", name, err.Error(), delay)
+			time.Sleep(delay)
+		}
+		if err == nil && client.recorder != nil {
+			if recordErr := client.recorder.Record(name, args, data); recordErr != nil {
+				Log.Error("%s: unable to record the call: %s", name, recordErr.Error())
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	response := xmlrpc.Response(data)
+	if err := response.Err(); err != nil {
+		if fault, ok := err.(xmlrpc.FaultError); ok {
+			return nil, &FaultError{Code: fault.Code, Method: name, Message: fault.String}
+		}
+		return nil, err
 	}
 
 	var result interface{}
-	err := client.connection.Call(name, args, &result)
+	if err := response.Unmarshal(&result); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return result, nil
+}
+
+// Request a function call on the remote. It is safe to call concurrently. Session token is passed as the first argument
+// to all the functions, except those which are called without authentication. Responses of read-only functions
+// are served from the cache, while they are fresh.
+func (client *rpcClient) RequestFuction(name string, args ...interface{}) (interface{}, error) {
+	if result, exist := client.cache.Get(name, args); exist {
+		return result, nil
+	}
+	result, err := client.request(name, args)
+	if err == nil {
+		client.cache.Put(name, args, result)
+	}
+	client.cache.Invalidate(name)
+
+	return result, err
+}
+
+// Request a function call on the remote, passing the session token
+func (client *rpcClient) request(name string, args []interface{}) (interface{}, error) {
+	if isSessionless(name) {
+		return client.call(name, args)
+	}
 
+	session, err := client.GetSession()
 	if err != nil {
-		if !client.inUse {
-			client.auth()
-			// Repeat it again with replaced first element, which is always session token
-			nArgs := make([]interface{}, len(args))
-			nArgs[0] = client.session
-			Console.CheckError(client.connection.Call(name, nArgs, &result))
-		} else {
-			Console.CheckError(err)
+		return nil, err
+	}
+	result, err := client.call(name, append([]interface{}{session}, args...))
+
+	if fault, ok := err.(*FaultError); ok && fault.IsAuth() {
+		// Session is expired or invalid: login again and repeat the call with the new session
+		if session, err = client.relogin(session); err != nil {
+			return nil, err
 		}
+		result, err = client.call(name, append([]interface{}{session}, args...))
 	}
-	client.inUse = true
 
-	return result
+	return result, err
 }
 
-var RPC rpcClient
+// Functions, called without session token
+var sessionlessFunctions = []string{"auth.login", "api.getVersion", "api.systemVersion"}
 
-func init() {
-	RPC = *RPCClient()
+// Tell if the function is called without session token
+func isSessionless(name string) bool {
+	return funk.ContainsString(sessionlessFunctions, name)
 }
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FaultError is returned when the XML-RPC server responds with a fault
+type FaultError struct {
+	Code    int
+	Method  string
+	Message string
+}
+
+// Error message of the fault
+func (fault *FaultError) Error() string {
+	return fmt.Sprintf("%s: %s (fault %d)", fault.Method, fault.Message, fault.Code)
+}
+
+// Tell if the fault message contains any of the phrases
+func (fault *FaultError) mentions(phrases ...string) bool {
+	message := strings.ToLower(fault.Message)
+	for _, phrase := range phrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds of the faults
+const (
+	faultAuth       = "auth"
+	faultNotFound   = "not found"
+	faultPermission = "permission"
+	faultInvalid    = "invalid"
+)
+
+// Kinds of the faults by their codes, e.g. 1200 is "No such channel" and 1201 is an invalid channel label.
+// Faults with other codes are classified by their messages.
+var faultKinds = map[int]string{
+	1200: faultNotFound,
+	1201: faultInvalid,
+	1202: faultInvalid,
+	2100: faultNotFound,
+	2601: faultNotFound,
+	2950: faultAuth,
+}
+
+// Classify the fault by its code or, if the code is unknown, by its message
+func (fault *FaultError) kind() string {
+	if kind, exist := faultKinds[fault.Code]; exist {
+		return kind
+	}
+	switch {
+	case fault.mentions("session") && fault.mentions("invalid", "could not find", "expired"):
+		return faultAuth
+	case fault.mentions("no such", "not found", "does not exist", "unable to locate", "could not find"):
+		return faultNotFound
+	case fault.mentions("permission", "not authorized", "access denied") || fault.mentions("must be") && fault.mentions("admin"):
+		return faultPermission
+	}
+	return ""
+}
+
+// IsAuth tells if the fault is about authentication, e.g. invalid credentials or expired session
+func (fault *FaultError) IsAuth() bool {
+	return fault.kind() == faultAuth
+}
+
+// IsNotFound tells if the fault is about a missing object, e.g. channel, system or erratum
+func (fault *FaultError) IsNotFound() bool {
+	return fault.kind() == faultNotFound
+}
+
+// IsPermission tells if the fault is about insufficient permissions, e.g. "You must be a channel administrator"
+func (fault *FaultError) IsPermission() bool {
+	return fault.kind() == faultPermission
+}
+
+// AsFault returns the error as a fault of the XML-RPC server, if it is one
+func AsFault(err error) (*FaultError, bool) {
+	fault, ok := err.(*FaultError)
+	return fault, ok
+}
+
+// AsList returns result of the XML-RPC call as a list
+func AsList(result interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return []interface{}{}, nil
+	}
+	list, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unexpected response: list expected, got %T", result)
+	}
+	return list, nil
+}
+
+// AsStruct returns result of the XML-RPC call as a structure
+func AsStruct(result interface{}, err error) (map[string]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unexpected response: structure expected, got %T", result)
+	}
+	return data, nil
+}
+
+// AsString returns result of the XML-RPC call as a string
+func AsString(result interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	text, ok := result.(string)
+	if !ok {
+		return "", fmt.Errorf("Unexpected response: string expected, got %T", result)
+	}
+	return text, nil
+}
+
+// AsStructs returns result of the XML-RPC call as a list of structures, skipping other elements
+func AsStructs(result interface{}, err error) ([]map[string]interface{}, error) {
+	list, err := AsList(result, err)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, element := range list {
+		if item, ok := element.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
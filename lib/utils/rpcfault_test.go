@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestFaultKinds(t *testing.T) {
+	for _, test := range []struct {
+		fault      FaultError
+		auth       bool
+		notFound   bool
+		permission bool
+	}{
+		{FaultError{Code: 2950, Message: "Either the password or username is incorrect."}, true, false, false},
+		{FaultError{Code: -1, Message: "Could not find session with id: 123x"}, true, false, false},
+		{FaultError{Code: 1200, Message: "No such channel: dev-sles"}, false, true, false},
+		{FaultError{Code: -210, Message: "Could not find server 1000010000 for user admin"}, false, true, false},
+		{FaultError{Code: 1201, Message: "Channel label must be specified"}, false, false, false},
+		{FaultError{Code: 1202, Message: "Channel \"sles\" has child channels and must be deleted after them"}, false, false, false},
+		{FaultError{Code: -1, Message: "Label must be at least 6 characters long"}, false, false, false},
+		{FaultError{Code: -1, Message: "You must be a channel administrator to modify the channel"}, false, false, true},
+		{FaultError{Code: -1, Message: "Permission check failed"}, false, false, true},
+	} {
+		if test.fault.IsAuth() != test.auth || test.fault.IsNotFound() != test.notFound || test.fault.IsPermission() != test.permission {
+			t.Errorf("%s: expected auth %t, not found %t, permission %t, got %t, %t, %t", test.fault.Message,
+				test.auth, test.notFound, test.permission, test.fault.IsAuth(), test.fault.IsNotFound(), test.fault.IsPermission())
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/*
+Timeouts and retries of the XML-RPC calls, configured in the server section:
+
+	connect_timeout: 10s      connecting to the server, including TLS handshake
+	read_timeout:    5m       waiting for the complete response of a call
+	retries:         3        attempts after a transient transport error, only failures to connect for changing calls
+	retry_delay:     1s       first delay between attempts, doubled each time up to 30s
+	method_timeouts:          read timeout of specific methods, e.g. long-running merges
+	  channel.software.mergePackages: 1h
+
+Durations are either Go durations ("90s", "1h") or plain seconds.
+*/
+type callPolicy struct {
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	retries        int
+	retryDelay     time.Duration
+	maxRetryDelay  time.Duration
+	methodTimeouts map[string]time.Duration
+}
+
+// Methods, which might run much longer than the usual read timeout on large channels
+var longRunningMethods = []string{
+	"channel.software.mergePackages",
+	"channel.software.mergeErrata",
+	"channel.software.clone",
+	"channel.software.addPackages",
+	"channel.software.removePackages",
+	"channel.software.removeErrata",
+	"channel.software.listAllPackages",
+}
+
+// Parse duration option, given as Go duration or seconds
+func durationOption(config map[interface{}]interface{}, key string, fallback time.Duration) (time.Duration, error) {
+	switch value := config[key].(type) {
+	case nil:
+		return fallback, nil
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case float64:
+		return time.Duration(value * float64(time.Second)), nil
+	case string:
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return 0, fmt.Errorf("Option \"%s\" should be a duration, e.g. \"30s\": %s", key, err.Error())
+		}
+		return duration, nil
+	default:
+		return 0, fmt.Errorf("Option \"%s\" should be a duration, e.g. \"30s\"", key)
+	}
+}
+
+// Read timeouts and retries from the server configuration
+func newCallPolicy(serverConfig map[interface{}]interface{}) (*callPolicy, error) {
+	policy := &callPolicy{
+		retries:        3,
+		maxRetryDelay:  30 * time.Second,
+		methodTimeouts: make(map[string]time.Duration),
+	}
+
+	var err error
+	if policy.connectTimeout, err = durationOption(serverConfig, "connect_timeout", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if policy.readTimeout, err = durationOption(serverConfig, "read_timeout", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if policy.retryDelay, err = durationOption(serverConfig, "retry_delay", time.Second); err != nil {
+		return nil, err
+	}
+	if retries, exist := serverConfig["retries"]; exist {
+		count, ok := retries.(int)
+		if !ok || count < 0 {
+			return nil, errors.New("Option \"retries\" should be a non-negative number")
+		}
+		policy.retries = count
+	}
+
+	for _, method := range longRunningMethods {
+		policy.methodTimeouts[method] = time.Hour
+	}
+	if overrides, exist := serverConfig["method_timeouts"].(map[interface{}]interface{}); exist {
+		for method := range overrides {
+			name := fmt.Sprint(method)
+			if policy.methodTimeouts[name], err = durationOption(overrides, name, policy.readTimeout); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// Get read timeout of the method
+func (policy *callPolicy) timeoutOf(method string) time.Duration {
+	if timeout, exist := policy.methodTimeouts[method]; exist {
+		return timeout
+	}
+	return policy.readTimeout
+}
+
+// Get delay before the next attempt: it is doubled after each failed attempt
+func (policy *callPolicy) delay(attempt int) time.Duration {
+	delay := policy.retryDelay
+	for idx := 0; idx < attempt && delay < policy.maxRetryDelay; idx++ {
+		delay *= 2
+	}
+	if delay > policy.maxRetryDelay {
+		delay = policy.maxRetryDelay
+	}
+	return delay
+}
+
+// Error of the HTTP transport, which might be gone on the next attempt
+type transientError struct {
+	err error
+}
+
+func (transient *transientError) Error() string {
+	return transient.err.Error()
+}
+
+/*
+Tell if the call should be repeated after the transport error. Calls, which change something on the server,
+are repeated only if the request has not reached it. Otherwise the server might have already applied the change,
+and repeating it would apply it twice or fail, because it is already done.
+*/
+func isRetriable(method string, err error) bool {
+	if isWriteMethod(method) {
+		return isUndelivered(err)
+	}
+	return isTransient(err)
+}
+
+// Tell if the transport error happened before the request was sent, because the server could not be connected
+func isUndelivered(err error) bool {
+	if err == nil {
+		return false
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		if opErr.Op == "dial" {
+			return true
+		}
+		if sysErr, ok := opErr.Err.(*os.SyscallError); ok && sysErr.Err == syscall.ECONNREFUSED {
+			return true
+		}
+	}
+	return strings.Contains(err.Error(), "connection refused")
+}
+
+/*
+Tell if the transport error is transient. These are failures to connect, connections dropped
+by the server or proxy, and gateway errors. Read timeouts are not transient: the server might
+still process the call, and repeating a long-running call only loads it more.
+*/
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(*transientError); ok {
+		return true
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		if opErr.Op == "dial" {
+			return true
+		}
+		if sysErr, ok := opErr.Err.(*os.SyscallError); ok {
+			switch sysErr.Err {
+			case syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.EPIPE:
+				return true
+			}
+		}
+	}
+	message := err.Error()
+	for _, phrase := range []string{"connection reset", "connection refused", "broken pipe", "EOF", "TLS handshake timeout"} {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
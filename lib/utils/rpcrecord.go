@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/kolo/xmlrpc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+rpcRecorder keeps XML-RPC calls on disk to reproduce problems without a live server.
+
+With --record <dir> every call and the raw server response are stored as a pair of files
+"000001-<method>.request.xml" and "000001-<method>.response.xml". Session token and password
+are replaced by a placeholder, both in requests and in the "auth.login" response.
+All clients of the command (e.g. of --to-server) share one sequence of calls per directory,
+recording into a directory with calls continues their numbering.
+
+With --replay <dir> nothing is sent to the network: responses are taken from the recording
+by the same (redacted) request. Repeated identical requests get the recorded responses in
+their order, and the last one after these are exhausted.
+*/
+type rpcRecorder struct {
+	directory string
+	replay    bool
+	sequence  int
+	responses map[string][][]byte
+	lock      sync.Mutex
+}
+
+// Placeholder of the session token and password in the recording
+const redactedValue = "********"
+
+// Command line choice of recording or replaying calls
+var recordDirectory, replayDirectory string
+
+// SetRecording makes all clients, connected afterwards, record calls into the directory or replay them from it
+func SetRecording(record string, replay string) error {
+	if record != "" && replay != "" {
+		return fmt.Errorf("Calls cannot be recorded and replayed at the same time")
+	}
+	recordDirectory, replayDirectory = record, replay
+	return nil
+}
+
+// Recorders by their directories, shared by all clients
+var (
+	recorders     = make(map[string]*rpcRecorder)
+	recordersLock sync.Mutex
+)
+
+// Get recorder, selected on the command line. Returns nil, if calls are neither recorded nor replayed.
+func newRPCRecorder() (*rpcRecorder, error) {
+	directory, replay := recordDirectory, false
+	if directory == "" {
+		directory, replay = replayDirectory, true
+	}
+	if directory == "" {
+		return nil, nil
+	}
+	directory = Configuration.ExpandPath(directory)
+
+	recordersLock.Lock()
+	defer recordersLock.Unlock()
+
+	if recorder, exist := recorders[directory]; exist && recorder.replay == replay {
+		return recorder, nil
+	}
+	recorder := &rpcRecorder{directory: directory, replay: replay}
+	var err error
+	if replay {
+		err = recorder.load()
+	} else {
+		err = recorder.create()
+	}
+	if err != nil {
+		return nil, err
+	}
+	recorders[directory] = recorder
+	return recorder, nil
+}
+
+// Get number of the recorded call from its file name, e.g. 12 from "000012-api.getVersion.request.xml"
+func callNumber(filename string) int {
+	name := filepath.Base(filename)
+	if dash := strings.Index(name, "-"); dash > 0 {
+		if number, err := strconv.Atoi(name[:dash]); err == nil {
+			return number
+		}
+	}
+	return 0
+}
+
+// Get request files of the recorded calls in their order
+func (recorder *rpcRecorder) requestFiles() ([]string, error) {
+	requests, err := filepath.Glob(filepath.Join(recorder.directory, "*.request.xml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return callNumber(requests[i]) < callNumber(requests[j])
+	})
+	return requests, nil
+}
+
+// Create the recording directory and continue numbering of the calls already recorded there
+func (recorder *rpcRecorder) create() error {
+	if err := os.MkdirAll(recorder.directory, 0700); err != nil {
+		return fmt.Errorf("Unable to create recording directory: %s", err.Error())
+	}
+	requests, err := recorder.requestFiles()
+	if err != nil {
+		return err
+	}
+	if len(requests) > 0 {
+		recorder.sequence = callNumber(requests[len(requests)-1])
+	}
+	return nil
+}
+
+// Tell if the calls are served from the recording
+func (recorder *rpcRecorder) replaying() bool {
+	return recorder != nil && recorder.replay
+}
+
+// Load recorded responses, keyed by their requests
+func (recorder *rpcRecorder) load() error {
+	requests, err := recorder.requestFiles()
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		return fmt.Errorf("No recorded calls found in \"%s\"", recorder.directory)
+	}
+
+	recorder.responses = make(map[string][][]byte)
+	for _, requestFile := range requests {
+		request, err := ioutil.ReadFile(requestFile)
+		if err != nil {
+			return err
+		}
+		response, err := ioutil.ReadFile(strings.TrimSuffix(requestFile, ".request.xml") + ".response.xml")
+		if err != nil {
+			return err
+		}
+		recorder.responses[string(request)] = append(recorder.responses[string(request)], response)
+	}
+	return nil
+}
+
+// Encode the call with the session token or password replaced by the placeholder
+func (recorder *rpcRecorder) redactedRequest(name string, args []interface{}) ([]byte, error) {
+	redacted := append([]interface{}{}, args...)
+	switch {
+	case name == "auth.login" && len(redacted) > 1:
+		redacted[1] = redactedValue
+	case !isSessionless(name) && len(redacted) > 0:
+		redacted[0] = redactedValue
+	}
+	return xmlrpc.EncodeMethodCall(name, redacted...)
+}
+
+// Store the call and the raw response of the server
+func (recorder *rpcRecorder) Record(name string, args []interface{}, response []byte) error {
+	request, err := recorder.redactedRequest(name, args)
+	if err != nil {
+		return err
+	}
+	if name == "auth.login" && xmlrpc.Response(response).Err() == nil {
+		response = []byte("<?xml version=\"1.0\"?><methodResponse><params><param><value><string>" +
+			redactedValue + "</string></value></param></params></methodResponse>")
+	}
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.sequence++
+	prefix := filepath.Join(recorder.directory, fmt.Sprintf("%06d-%s", recorder.sequence, name))
+	if err := ioutil.WriteFile(prefix+".request.xml", request, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(prefix+".response.xml", response, 0600)
+}
+
+// Get the recorded raw response of the call
+func (recorder *rpcRecorder) Replay(name string, args []interface{}) ([]byte, error) {
+	request, err := recorder.redactedRequest(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	responses := recorder.responses[string(request)]
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded response in \"%s\"", recorder.directory)
+	}
+	if len(responses) > 1 {
+		recorder.responses[string(request)] = responses[1:]
+	}
+	return responses[0], nil
+}
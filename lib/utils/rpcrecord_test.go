@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Response with the string value
+func stringResponse(value string) []byte {
+	return []byte("<?xml version=\"1.0\"?><methodResponse><params><param><value><string>" + value +
+		"</string></value></param></params></methodResponse>")
+}
+
+func TestRecordingContinuesNumbering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spaceman-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Calls of an earlier run
+	for _, name := range []string{"0009-api.getVersion.request.xml", "0009-api.getVersion.response.xml"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SetRecording(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer SetRecording("", "")
+	// Clients of the source and the target server
+	for _, label := range []string{"dev-sles", "uat-sles"} {
+		recorder, err := newRPCRecorder()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := recorder.Record("channel.software.getDetails", []interface{}{"session", label}, stringResponse(label)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"000010-channel.software.getDetails.request.xml", "000011-channel.software.getDetails.request.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReplayInNumericOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spaceman-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	recorder := &rpcRecorder{directory: dir, sequence: 9998}
+	for _, version := range []string{"first", "second", "third"} {
+		if err := recorder.Record("api.getVersion", []interface{}{}, stringResponse(version)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Recordings of earlier versions have narrower numbers, which sort after the wider ones as strings
+	if err := os.Rename(filepath.Join(dir, "009999-api.getVersion.request.xml"), filepath.Join(dir, "9999-api.getVersion.request.xml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "009999-api.getVersion.response.xml"), filepath.Join(dir, "9999-api.getVersion.response.xml")); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := &rpcRecorder{directory: dir, replay: true}
+	if err := replay.load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"first", "second", "third"} {
+		response, err := replay.Replay("api.getVersion", []interface{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(response) != string(stringResponse(expected)) {
+			t.Errorf("expected %s response, got %s", expected, response)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+TLS and proxy settings of the server section:
+
+	insecure:        true               don't verify the server certificate
+	ca_file:         /etc/pki/uyuni.pem CA certificates (PEM) to verify the server, in addition to the system ones
+	client_cert:     ~/.uyuni/me.crt    client certificate (PEM), e.g. required by a reverse proxy
+	client_key:      ~/.uyuni/me.key    private key of the client certificate (PEM)
+	tls_min_version: "1.2"              minimal TLS version: 1.0, 1.1, 1.2 or 1.3
+	server_name:     uyuni.example.com  host name to verify the certificate against, if it differs from the URL
+	proxy:           http://proxy:3128  HTTP(S) proxy, "none" for direct connection. Default is from the environment.
+*/
+
+// TLS versions by their names in the configuration
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Get string option of the server configuration
+func stringOption(config map[interface{}]interface{}, key string) string {
+	if value, exist := config[key]; exist && value != nil {
+		return strings.TrimSpace(fmt.Sprint(value))
+	}
+	return ""
+}
+
+// Build TLS configuration of the server connection
+func newTLSConfig(serverConfig map[interface{}]interface{}) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: stringOption(serverConfig, "server_name"),
+	}
+	config.InsecureSkipVerify, _ = serverConfig["insecure"].(bool)
+
+	if caFile := stringOption(serverConfig, "ca_file"); caFile != "" {
+		pem, err := ioutil.ReadFile(Configuration.ExpandPath(caFile))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA file: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file \"%s\" contains no PEM certificates", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	certFile, keyFile := stringOption(serverConfig, "client_cert"), stringOption(serverConfig, "client_key")
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("Both \"client_cert\" and \"client_key\" must be specified for the client certificate")
+		}
+		certificate, err := tls.LoadX509KeyPair(Configuration.ExpandPath(certFile), Configuration.ExpandPath(keyFile))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if version := stringOption(serverConfig, "tls_min_version"); version != "" {
+		minVersion, exist := tlsVersions[strings.TrimPrefix(strings.ToLower(version), "tls")]
+		if !exist {
+			return nil, fmt.Errorf("Unknown TLS version \"%s\", should be one of 1.0, 1.1, 1.2 or 1.3", version)
+		}
+		config.MinVersion = minVersion
+	}
+
+	return config, nil
+}
+
+// Get proxy of the server connection
+func proxyOption(serverConfig map[interface{}]interface{}) (func(*http.Request) (*url.URL, error), error) {
+	proxy := stringOption(serverConfig, "proxy")
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "none":
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("Proxy \"%s\" should be an URL, e.g. \"http://proxy:3128\"", proxy)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
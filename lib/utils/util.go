@@ -79,6 +79,84 @@ func (cns *console) ExitOnUnknown(message string) {
 	cns.ExitOnStderr(fmt.Sprintf("%s %s", message, cns.hint))
 }
 
+// Exit on the error of the API call. Missing objects are reported by their description, e.g. "Channel \"label\"".
+func (cns *console) CheckFault(err error, object string) {
+	if fault, ok := AsFault(err); ok && fault.IsNotFound() {
+		cns.ExitOnStderr(fmt.Sprintf("%s does not exist", object))
+	}
+	cns.CheckError(err)
+}
+
+// SplitList splits comma-separated option value, skipping empty elements
+func SplitList(value string) []string {
+	values := make([]string, 0)
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			values = append(values, element)
+		}
+	}
+	return values
+}
+
+// ChannelLabels tells, if software channels exist on the server. Channels are listed on the first check.
+type ChannelLabels struct {
+	labels map[string]bool
+}
+
+// Exists checks if channel exists on the server
+func (channels *ChannelLabels) Exists(label string) bool {
+	if channels.labels == nil {
+		list, err := AsStructs(RPC.RequestFuction("channel.listSoftwareChannels"))
+		Console.CheckError(err)
+		channels.labels = make(map[string]bool)
+		for _, channel := range list {
+			channels.labels[fmt.Sprint(channel["label"])] = true
+		}
+	}
+	return channels.labels[label]
+}
+
+// Parallel runs the task for every index up to the count, jobs of them at the same time. The done function,
+// if given, is called for every index in order, as soon as its task has finished. Returns the first error in order.
+func Parallel(count int, jobs int, task func(idx int) error, done func(idx int, err error)) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	errs := make([]error, count)
+	finished := make([]chan struct{}, count)
+	for idx := range finished {
+		finished[idx] = make(chan struct{})
+	}
+
+	queue := make(chan int)
+	for job := 0; job < jobs; job++ {
+		go func() {
+			for idx := range queue {
+				errs[idx] = task(idx)
+				close(finished[idx])
+			}
+		}()
+	}
+	go func() {
+		for idx := 0; idx < count; idx++ {
+			queue <- idx
+		}
+		close(queue)
+	}()
+
+	var first error
+	for idx := 0; idx < count; idx++ {
+		<-finished[idx]
+		if done != nil {
+			done(idx, errs[idx])
+		}
+		if first == nil {
+			first = errs[idx]
+		}
+	}
+	return first
+}
+
 // Console instance
 var Console console
 
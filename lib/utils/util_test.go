@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParallelReportsInOrder(t *testing.T) {
+	order := make([]int, 0)
+	err := Parallel(10, 4, func(idx int) error {
+		if idx%3 == 2 {
+			return fmt.Errorf("task %d failed", idx)
+		}
+		return nil
+	}, func(idx int, err error) {
+		order = append(order, idx)
+	})
+	if expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(expected, order) {
+		t.Errorf("expected tasks reported in order %v, got %v", expected, order)
+	}
+	if err == nil || err.Error() != "task 2 failed" {
+		t.Errorf("expected error of the first failed task, got %v", err)
+	}
+	if err := Parallel(0, 4, nil, nil); err != nil {
+		t.Errorf("expected no error without tasks, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/thoas/go-funk"
+	"github.com/urfave/cli"
+	"strings"
+)
+
+/*
+Workflow describes lifecycle phases of the channels
+and the naming rules of their labels.
+*/
+type Workflow struct {
+	name      string
+	preset    bool
+	phases    []string
+	excluded  []string
+	filtered  []string
+	delimiter string
+}
+
+// NewWorkflow constructor. Loads configured workflow by name or falls back to the preset default one.
+func NewWorkflow(ctx *cli.Context, name string) (*Workflow, error) {
+	wf := new(Workflow)
+	wf.delimiter = "-"
+	if name == "" {
+		name = "default"
+	}
+	wf.name = name
+
+	workflowConfig := wf.lookupConfig(ctx)
+	if workflowConfig == nil {
+		wf.preset = true
+		wf.phases = []string{"dev", "uat", "prod"}
+		return wf, nil
+	}
+
+	wf.phases = wf.toStrings(workflowConfig["phases"])
+	if len(wf.phases) == 0 {
+		return nil, fmt.Errorf("Phases are not configured in workflow \"%s\"", name)
+	}
+	wf.excluded = wf.toStrings(workflowConfig["exclude"])
+	wf.filtered = wf.toStrings(workflowConfig["filter"])
+
+	if delimiter, ok := workflowConfig["delimiter"].(string); ok && delimiter != "" {
+		wf.delimiter = delimiter
+	}
+
+	return wf, nil
+}
+
+// Find workflow section in the "lifecycle" configuration
+func (wf *Workflow) lookupConfig(ctx *cli.Context) map[interface{}]interface{} {
+	section, exist := Configuration.LookupSection(ctx, "lifecycle")
+	if !exist {
+		return nil
+	}
+	lifecycleConfig, ok := section.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	workflowsConfig, ok := lifecycleConfig["workflows"].(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	workflowConfig, ok := workflowsConfig[wf.name].(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	return workflowConfig
+}
+
+func (wf *Workflow) toStrings(data interface{}) []string {
+	values := make([]string, 0)
+	if items, ok := data.([]interface{}); ok {
+		for _, item := range items {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+	}
+	return values
+}
+
+// Name of the workflow
+func (wf *Workflow) Name() string {
+	return wf.name
+}
+
+// IsPreset tells if the workflow is not configured and the default phases are used
+func (wf *Workflow) IsPreset() bool {
+	return wf.preset
+}
+
+// Phases of the workflow, in the order of promotion
+func (wf *Workflow) Phases() []string {
+	return wf.phases
+}
+
+// Excluded channel patterns
+func (wf *Workflow) Excluded() []string {
+	return wf.excluded
+}
+
+// Filtered channel prefixes
+func (wf *Workflow) Filtered() []string {
+	return wf.filtered
+}
+
+// Delimiter between phase and channel name
+func (wf *Workflow) Delimiter() string {
+	return wf.delimiter
+}
+
+// PhaseOf returns a phase name from the given channel label or an empty string, if channel has no phase.
+func (wf *Workflow) PhaseOf(label string) string {
+	for _, phase := range wf.phases {
+		prefix := phase + wf.delimiter
+		if len(label) > len(prefix) && strings.HasPrefix(label, prefix) {
+			return phase
+		}
+	}
+	return ""
+}
+
+// Relabel returns a label of the same channel in another phase of the workflow
+func (wf *Workflow) Relabel(label string, phase string) (string, error) {
+	if !funk.ContainsString(wf.phases, phase) {
+		return "", fmt.Errorf("Phase \"%s\" is not in workflow \"%s\"", phase, wf.name)
+	}
+	current := wf.PhaseOf(label)
+	if current == "" {
+		return "", fmt.Errorf("Channel \"%s\" does not belong to any phase of workflow \"%s\"", label, wf.name)
+	}
+	return phase + wf.delimiter + label[len(current)+len(wf.delimiter):], nil
+}
@@ -0,0 +1,191 @@
+package uyuni
+
+import (
+	"time"
+)
+
+// ListSoftwareChannels returns all software channels, visible to the user
+func (client *Client) ListSoftwareChannels() ([]Channel, error) {
+	records, err := client.callList("channel.listSoftwareChannels")
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, 0, len(records))
+	for _, data := range records {
+		channels = append(channels, newChannel(data))
+	}
+	return channels, nil
+}
+
+// ChannelDetails returns details of the channel
+func (client *Client) ChannelDetails(label string) (*ChannelDetails, error) {
+	data, err := client.callStruct("channel.software.getDetails", label)
+	if err != nil {
+		return nil, err
+	}
+	return newChannelDetails(data), nil
+}
+
+// SetChannelDetails changes the channel metadata, e.g. "name", "summary" or "description"
+func (client *Client) SetChannelDetails(id int, changes map[string]interface{}) error {
+	return client.call("channel.software.setDetails", id, changes)
+}
+
+// CreateChannel creates a new software channel. Parent label is empty for base channels.
+func (client *Client) CreateChannel(label string, name string, summary string, arch string, parent string, checksum string) error {
+	return client.call("channel.software.create", label, name, summary, arch, parent, checksum)
+}
+
+// CloneChannel clones the channel. Details contain "label", "name", "summary" and "parent_label" of the clone.
+func (client *Client) CloneChannel(label string, details map[string]interface{}, originalState bool) error {
+	return client.call("channel.software.clone", label, details, originalState)
+}
+
+// DeleteChannel deletes the channel
+func (client *Client) DeleteChannel(label string) error {
+	return client.call("channel.software.delete", label)
+}
+
+// ListAllPackages returns all packages of the channel
+func (client *Client) ListAllPackages(label string) ([]Package, error) {
+	records, err := client.callList("channel.software.listAllPackages", label)
+	if err != nil {
+		return nil, err
+	}
+	return client.packages(records), nil
+}
+
+// AddPackages adds packages to the channel by their IDs
+func (client *Client) AddPackages(label string, ids []int) error {
+	return client.call("channel.software.addPackages", label, ids)
+}
+
+// RemovePackages removes packages from the channel by their IDs
+func (client *Client) RemovePackages(label string, ids []int) error {
+	return client.call("channel.software.removePackages", label, ids)
+}
+
+// MergePackages merges all packages from one channel into another. Returns number of merged packages.
+func (client *Client) MergePackages(labelSrc string, labelDst string) (int, error) {
+	merged, err := client.callList("channel.software.mergePackages", labelSrc, labelDst)
+	return len(merged), err
+}
+
+// Decode list of errata
+func (client *Client) errata(records []record) []Erratum {
+	errata := make([]Erratum, 0, len(records))
+	for _, data := range records {
+		errata = append(errata, newErratum(data))
+	}
+	return errata
+}
+
+// ListErrata returns all errata of the channel
+func (client *Client) ListErrata(label string) ([]Erratum, error) {
+	records, err := client.callList("channel.software.listErrata", label)
+	if err != nil {
+		return nil, err
+	}
+	return client.errata(records), nil
+}
+
+// ListErrataBetween returns errata of the channel, issued in the time range
+func (client *Client) ListErrataBetween(label string, since time.Time, until time.Time) ([]Erratum, error) {
+	records, err := client.callList("channel.software.listErrata", label, since, until)
+	if err != nil {
+		return nil, err
+	}
+	return client.errata(records), nil
+}
+
+// MergeErrata merges errata from one channel into another. If advisories are given, only those are merged.
+// Returns number of merged errata.
+func (client *Client) MergeErrata(labelSrc string, labelDst string, advisories ...string) (int, error) {
+	var merged []record
+	var err error
+	if len(advisories) > 0 {
+		merged, err = client.callList("channel.software.mergeErrata", labelSrc, labelDst, advisories)
+	} else {
+		merged, err = client.callList("channel.software.mergeErrata", labelSrc, labelDst)
+	}
+	return len(merged), err
+}
+
+// RemoveErrata removes errata from the channel, optionally with their packages
+func (client *Client) RemoveErrata(label string, advisories []string, removePackages bool) error {
+	return client.call("channel.software.removeErrata", label, advisories, removePackages)
+}
+
+// Decode list of content sources
+func (client *Client) contentSources(records []record) []ContentSource {
+	sources := make([]ContentSource, 0, len(records))
+	for _, data := range records {
+		sources = append(sources, newContentSource(data))
+	}
+	return sources
+}
+
+// ListChannelRepos returns repositories, associated with the channel
+func (client *Client) ListChannelRepos(label string) ([]ContentSource, error) {
+	records, err := client.callList("channel.software.listChannelRepos", label)
+	if err != nil {
+		return nil, err
+	}
+	return client.contentSources(records), nil
+}
+
+// ListUserRepos returns all repositories of the user
+func (client *Client) ListUserRepos() ([]ContentSource, error) {
+	records, err := client.callList("channel.software.listUserRepos")
+	if err != nil {
+		return nil, err
+	}
+	return client.contentSources(records), nil
+}
+
+// CreateRepo creates a new repository of the type, e.g. "yum"
+func (client *Client) CreateRepo(label string, repoType string, url string) error {
+	return client.call("channel.software.createRepo", label, repoType, url)
+}
+
+// UpdateRepoURL changes URL of the repository
+func (client *Client) UpdateRepoURL(label string, url string) error {
+	return client.call("channel.software.updateRepoUrl", label, url)
+}
+
+// UpdateRepoLabel renames the repository
+func (client *Client) UpdateRepoLabel(label string, newLabel string) error {
+	return client.call("channel.software.updateRepoLabel", label, newLabel)
+}
+
+// AssociateRepo associates the repository with the channel
+func (client *Client) AssociateRepo(channel string, repo string) error {
+	return client.call("channel.software.associateRepo", channel, repo)
+}
+
+// DisassociateRepo disassociates the repository from the channel
+func (client *Client) DisassociateRepo(channel string, repo string) error {
+	return client.call("channel.software.disassociateRepo", channel, repo)
+}
+
+// SyncRepo triggers synchronisation of the channel repositories immediately,
+// or schedules it, if a cron expression is given
+func (client *Client) SyncRepo(channel string, cronExpr ...string) error {
+	if len(cronExpr) > 0 && cronExpr[0] != "" {
+		return client.call("channel.software.syncRepo", channel, cronExpr[0])
+	}
+	return client.call("channel.software.syncRepo", channel)
+}
+
+// ListSubscribedSystems returns systems, subscribed to the channel
+func (client *Client) ListSubscribedSystems(label string) ([]System, error) {
+	records, err := client.callList("channel.software.listSubscribedSystems", label)
+	if err != nil {
+		return nil, err
+	}
+	systems := make([]System, 0, len(records))
+	for _, data := range records {
+		systems = append(systems, newSystem(data))
+	}
+	return systems, nil
+}
@@ -0,0 +1,156 @@
+package uyuni
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requester performs XML-RPC calls on the server, passing the session automatically
+type Requester interface {
+	RequestFuction(name string, args ...interface{}) (interface{}, error)
+}
+
+// Client of the Uyuni API with typed responses
+type Client struct {
+	rpc Requester
+}
+
+// NewClient constructor
+func NewClient(rpc Requester) *Client {
+	client := new(Client)
+	client.rpc = rpc
+	return client
+}
+
+// Call the API function, which returns a structure
+func (client *Client) callStruct(name string, args ...interface{}) (record, error) {
+	result, err := client.rpc.RequestFuction(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: structure expected, got %T", name, result)
+	}
+	return record(data), nil
+}
+
+// Call the API function, which returns a list of structures. Elements of other types are skipped.
+func (client *Client) callList(name string, args ...interface{}) ([]record, error) {
+	result, err := client.rpc.RequestFuction(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return []record{}, nil
+	}
+	list, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: list expected, got %T", name, result)
+	}
+	records := make([]record, 0, len(list))
+	for _, element := range list {
+		if data, ok := element.(map[string]interface{}); ok {
+			records = append(records, record(data))
+		}
+	}
+	return records, nil
+}
+
+// Call the API function, which returns a list of strings
+func (client *Client) callStrings(name string, args ...interface{}) ([]string, error) {
+	result, err := client.rpc.RequestFuction(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := result.([]interface{}); !ok && result != nil {
+		return nil, fmt.Errorf("%s: list expected, got %T", name, result)
+	}
+	return record{name: result}.strs(name), nil
+}
+
+// Call the API function, ignoring its result
+func (client *Client) call(name string, args ...interface{}) error {
+	_, err := client.rpc.RequestFuction(name, args...)
+	return err
+}
+
+// Structure of the API response. Getters never panic and return zero values for missing or nil keys.
+type record map[string]interface{}
+
+// Get string value of the key
+func (data record) str(key string) string {
+	value, exist := data[key]
+	if !exist || value == nil {
+		return ""
+	}
+	if date, ok := value.(time.Time); ok {
+		return date.Format("2006-01-02 15:04:05")
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// Get string value of the first present key
+func (data record) first(keys ...string) string {
+	for _, key := range keys {
+		if value := data.str(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Get integer value of the key, which might be also sent as a string
+func (data record) integer(key string) int {
+	switch value := data[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case string:
+		number, _ := strconv.Atoi(strings.TrimSpace(value))
+		return number
+	}
+	return 0
+}
+
+// Get boolean value of the key
+func (data record) boolean(key string) bool {
+	switch value := data[key].(type) {
+	case bool:
+		return value
+	case int:
+		return value != 0
+	case string:
+		return value == "true" || value == "1"
+	}
+	return false
+}
+
+// Get list of strings of the key
+func (data record) strs(key string) []string {
+	values := make([]string, 0)
+	if list, ok := data[key].([]interface{}); ok {
+		for _, value := range list {
+			if value != nil {
+				values = append(values, fmt.Sprint(value))
+			}
+		}
+	}
+	return values
+}
+
+// Get list of structures of the key
+func (data record) records(key string) []record {
+	records := make([]record, 0)
+	if list, ok := data[key].([]interface{}); ok {
+		for _, element := range list {
+			if item, ok := element.(map[string]interface{}); ok {
+				records = append(records, record(item))
+			}
+		}
+	}
+	return records
+}
@@ -0,0 +1,301 @@
+package uyuni
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/isbm/spaceman/lib/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+FakeServer is an in-memory Uyuni server, which simulates channel lifecycle locally. It is selected
+by the "backend" option of the server section:
+
+	backend:    fake
+	user:       admin
+	password:   secret                 optional: if set, auth.login checks it
+	state_file: ~/uyuni-fake.json      optional: state is loaded from it and saved after every change
+
+Implemented are auth.*, channel.listSoftwareChannels and channel.software.* calls for channels,
+their packages and errata (create, clone, delete, list, add, merge, remove), as well as package
+lookups by NEVRA and errata.applicableToChannels. Repositories and systems are always empty.
+
+State file is JSON with the server content, e.g.:
+
+	{
+	  "channels": [{"id": 1, "label": "base", "name": "Base", "arch_label": "channel-x86_64",
+	                "packages": [1], "errata": ["SUSE-2020-1"]}],
+	  "packages": [{"id": 1, "name": "vim", "version": "8.0", "release": "1", "arch_label": "x86_64"}],
+	  "errata":   [{"id": 1, "advisory": "SUSE-2020-1", "type": "Security Advisory",
+	                "issue_date": "2020-01-31", "packages": [1]}]
+	}
+*/
+type FakeServer struct {
+	name      string
+	user      string
+	password  string
+	stateFile string
+	state     *fakeState
+	session   string
+	sessions  map[string]bool
+	lock      sync.Mutex
+}
+
+// Content of the fake server
+type fakeState struct {
+	Channels []*fakeChannel `json:"channels"`
+	Packages []*fakePackage `json:"packages"`
+	Errata   []*fakeErratum `json:"errata"`
+	LastID   int            `json:"last_id"`
+}
+
+type fakeChannel struct {
+	ID            int      `json:"id"`
+	Label         string   `json:"label"`
+	Name          string   `json:"name"`
+	Summary       string   `json:"summary"`
+	Description   string   `json:"description"`
+	Arch          string   `json:"arch_label"`
+	Checksum      string   `json:"checksum_label"`
+	ParentLabel   string   `json:"parent_label"`
+	CloneOriginal string   `json:"clone_original"`
+	Maintainer    string   `json:"maintainer_name"`
+	GPGKeyURL     string   `json:"gpg_key_url"`
+	LastModified  string   `json:"last_modified"`
+	Packages      []int    `json:"packages"`
+	Errata        []string `json:"errata"`
+}
+
+type fakePackage struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Epoch        string `json:"epoch"`
+	Version      string `json:"version"`
+	Release      string `json:"release"`
+	Arch         string `json:"arch_label"`
+	Checksum     string `json:"checksum"`
+	ChecksumType string `json:"checksum_type"`
+}
+
+type fakeErratum struct {
+	ID       int    `json:"id"`
+	Advisory string `json:"advisory"`
+	Type     string `json:"type"`
+	Synopsis string `json:"synopsis"`
+	Issued   string `json:"issue_date"`
+	Packages []int  `json:"packages"`
+}
+
+// NewFakeServer creates the fake server from the server configuration, loading its state file, if any
+func NewFakeServer(name string, serverConfig map[interface{}]interface{}) (*FakeServer, error) {
+	server := &FakeServer{
+		name:     name,
+		state:    new(fakeState),
+		sessions: make(map[string]bool),
+	}
+	server.user, _ = serverConfig["user"].(string)
+	server.password, _ = serverConfig["password"].(string)
+	if stateFile, _ := serverConfig["state_file"].(string); stateFile != "" {
+		server.stateFile = utils.Configuration.ExpandPath(strings.TrimSpace(stateFile))
+	}
+
+	if server.stateFile != "" {
+		data, err := ioutil.ReadFile(server.stateFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, server.state); err != nil {
+				return nil, fmt.Errorf("Fake server state \"%s\" is broken: %s", server.stateFile, err.Error())
+			}
+		}
+	}
+
+	return server, nil
+}
+
+func init() {
+	utils.RegisterBackend("fake", func(name string, serverConfig map[interface{}]interface{}) (utils.Backend, error) {
+		return NewFakeServer(name, serverConfig)
+	})
+}
+
+// GetURL returns pseudo URL of the fake server
+func (server *FakeServer) GetURL() string {
+	return "fake://" + server.name
+}
+
+// Login with the configured user and password
+func (server *FakeServer) Login() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	session, err := server.login(server.user, server.password)
+	if err != nil {
+		return err
+	}
+	server.session = session
+
+	return nil
+}
+
+// Logout from the current session
+func (server *FakeServer) Logout() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	delete(server.sessions, server.session)
+	server.session = ""
+
+	return nil
+}
+
+// RequestFuction calls the fake API function. Session is created on the first call, which requires it.
+func (server *FakeServer) RequestFuction(name string, args ...interface{}) (interface{}, error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	switch name {
+	case "auth.login":
+		params := fakeArgs(args)
+		return server.login(params.str(0), params.str(1))
+	case "auth.logout":
+		delete(server.sessions, fakeArgs(args).str(0))
+		return 1, nil
+	case "api.getVersion":
+		return "25", nil
+	case "api.systemVersion":
+		return "fake", nil
+	}
+
+	method, exist := fakeMethods[name]
+	if !exist {
+		return nil, &utils.FaultError{Code: -1, Method: name, Message: "Method is not implemented by the fake backend"}
+	}
+
+	if server.session == "" {
+		session, err := server.login(server.user, server.password)
+		if err != nil {
+			return nil, err
+		}
+		server.session = session
+	}
+
+	result, err := method.handler(server, fakeArgs(args))
+	if fault, ok := err.(*utils.FaultError); ok {
+		fault.Method = name
+	}
+	if err == nil && method.writes {
+		err = server.save()
+	}
+	return result, err
+}
+
+// Check the credentials and open a new session
+func (server *FakeServer) login(user string, password string) (string, error) {
+	if user != server.user || (server.password != "" && password != server.password) {
+		return "", &utils.FaultError{Code: 2950, Method: "auth.login", Message: "Either the password or username is incorrect."}
+	}
+	session := fmt.Sprintf("fake-%d-%d", time.Now().UnixNano(), len(server.sessions))
+	server.sessions[session] = true
+	return session, nil
+}
+
+// Save the state file, if it is configured
+func (server *FakeServer) save() error {
+	if server.stateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(server.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(server.stateFile), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(server.stateFile, data, 0600)
+}
+
+// Fault of the fake call. Method is filled in by the caller.
+func fakeFault(code int, format string, args ...interface{}) error {
+	return &utils.FaultError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Arguments of the fake call, passed either as Go values or decoded from XML-RPC
+type fakeArgs []interface{}
+
+// Get string argument
+func (args fakeArgs) str(idx int) string {
+	if idx >= len(args) || args[idx] == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(args[idx]))
+}
+
+// Get integer argument
+func (args fakeArgs) integer(idx int) int {
+	if idx < len(args) {
+		return record{"value": args[idx]}.integer("value")
+	}
+	return 0
+}
+
+// Get boolean argument
+func (args fakeArgs) boolean(idx int) bool {
+	if idx < len(args) {
+		return record{"value": args[idx]}.boolean("value")
+	}
+	return false
+}
+
+// Get time argument. Returns false, if it is missing.
+func (args fakeArgs) date(idx int) (time.Time, bool) {
+	if idx < len(args) {
+		date, ok := args[idx].(time.Time)
+		return date, ok
+	}
+	return time.Time{}, false
+}
+
+// Get structure argument
+func (args fakeArgs) structure(idx int) record {
+	if idx < len(args) {
+		if data, ok := args[idx].(map[string]interface{}); ok {
+			return record(data)
+		}
+	}
+	return record{}
+}
+
+// Get list of integers argument
+func (args fakeArgs) integers(idx int) []int {
+	values := make([]int, 0)
+	if idx >= len(args) {
+		return values
+	}
+	switch list := args[idx].(type) {
+	case []int:
+		values = append(values, list...)
+	case []interface{}:
+		for _, value := range list {
+			values = append(values, record{"value": value}.integer("value"))
+		}
+	}
+	return values
+}
+
+// Get list of strings argument
+func (args fakeArgs) strs(idx int) []string {
+	if idx >= len(args) {
+		return []string{}
+	}
+	if list, ok := args[idx].([]string); ok {
+		return append([]string{}, list...)
+	}
+	return record{"value": args[idx]}.strs("value")
+}
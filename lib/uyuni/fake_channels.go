@@ -0,0 +1,529 @@
+package uyuni
+
+import (
+	"time"
+)
+
+// Function of the fake API and whether it changes the state
+type fakeMethod struct {
+	handler func(server *FakeServer, args fakeArgs) (interface{}, error)
+	writes  bool
+}
+
+// Functions of the fake API by their names, called without the session argument
+var fakeMethods = map[string]fakeMethod{
+	"channel.listSoftwareChannels":           {(*FakeServer).listSoftwareChannels, false},
+	"channel.software.getDetails":            {(*FakeServer).getDetails, false},
+	"channel.software.setDetails":            {(*FakeServer).setDetails, true},
+	"channel.software.create":                {(*FakeServer).create, true},
+	"channel.software.clone":                 {(*FakeServer).clone, true},
+	"channel.software.delete":                {(*FakeServer).delete, true},
+	"channel.software.listChildren":          {(*FakeServer).listChildren, false},
+	"channel.software.listAllPackages":       {(*FakeServer).listAllPackages, false},
+	"channel.software.addPackages":           {(*FakeServer).addPackages, true},
+	"channel.software.removePackages":        {(*FakeServer).removePackages, true},
+	"channel.software.mergePackages":         {(*FakeServer).mergePackages, true},
+	"channel.software.listErrata":            {(*FakeServer).listErrata, false},
+	"channel.software.mergeErrata":           {(*FakeServer).mergeErrata, true},
+	"channel.software.removeErrata":          {(*FakeServer).removeErrata, true},
+	"channel.software.listChannelRepos":      {(*FakeServer).emptyList, false},
+	"channel.software.listUserRepos":         {(*FakeServer).emptyList, false},
+	"channel.software.listSubscribedSystems": {(*FakeServer).emptyList, false},
+	"packages.findByNvrea":                   {(*FakeServer).findByNvrea, false},
+	"packages.getDetails":                    {(*FakeServer).packageDetails, false},
+	"errata.applicableToChannels":            {(*FakeServer).applicableToChannels, false},
+}
+
+// Find channel by label
+func (server *FakeServer) channel(label string) (*fakeChannel, error) {
+	for _, channel := range server.state.Channels {
+		if channel.Label == label {
+			return channel, nil
+		}
+	}
+	return nil, fakeFault(1200, "No such channel: %s", label)
+}
+
+// Find package by ID
+func (server *FakeServer) pkg(id int) (*fakePackage, error) {
+	for _, pkg := range server.state.Packages {
+		if pkg.ID == id {
+			return pkg, nil
+		}
+	}
+	return nil, fakeFault(2100, "No such package: %d", id)
+}
+
+// Find erratum by advisory
+func (server *FakeServer) erratum(advisory string) (*fakeErratum, error) {
+	for _, erratum := range server.state.Errata {
+		if erratum.Advisory == advisory {
+			return erratum, nil
+		}
+	}
+	return nil, fakeFault(2601, "No such erratum: %s", advisory)
+}
+
+// Get next free ID of a channel
+func (server *FakeServer) nextID() int {
+	for _, channel := range server.state.Channels {
+		if channel.ID > server.state.LastID {
+			server.state.LastID = channel.ID
+		}
+	}
+	server.state.LastID++
+	return server.state.LastID
+}
+
+// Mark the channel as changed
+func (channel *fakeChannel) touch() {
+	channel.LastModified = time.Now().Format("2006-01-02 15:04:05")
+}
+
+// Tell if the channel contains the package
+func (channel *fakeChannel) hasPackage(id int) bool {
+	for _, pid := range channel.Packages {
+		if pid == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Tell if the channel contains the erratum
+func (channel *fakeChannel) hasErratum(advisory string) bool {
+	for _, name := range channel.Errata {
+		if name == advisory {
+			return true
+		}
+	}
+	return false
+}
+
+// Add packages, which are not in the channel yet
+func (channel *fakeChannel) addPackages(ids []int) []int {
+	added := make([]int, 0)
+	for _, id := range ids {
+		if !channel.hasPackage(id) {
+			channel.Packages = append(channel.Packages, id)
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// Remove packages from the channel
+func (channel *fakeChannel) removePackages(ids []int) {
+	removed := make(map[int]bool)
+	for _, id := range ids {
+		removed[id] = true
+	}
+	kept := make([]int, 0, len(channel.Packages))
+	for _, id := range channel.Packages {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	channel.Packages = kept
+}
+
+// Channel as listed by channel.listSoftwareChannels
+func (channel *fakeChannel) listed() map[string]interface{} {
+	return map[string]interface{}{
+		"label":         channel.Label,
+		"name":          channel.Name,
+		"parent_label":  channel.ParentLabel,
+		"arch":          channel.Arch,
+		"provider_name": "fake",
+		"packages":      len(channel.Packages),
+		"systems":       0,
+	}
+}
+
+// Package as listed by channel.software.listAllPackages
+func (pkg *fakePackage) listed() map[string]interface{} {
+	return map[string]interface{}{
+		"id":            pkg.ID,
+		"name":          pkg.Name,
+		"epoch":         pkg.Epoch,
+		"version":       pkg.Version,
+		"release":       pkg.Release,
+		"arch_label":    pkg.Arch,
+		"checksum":      pkg.Checksum,
+		"checksum_type": pkg.ChecksumType,
+	}
+}
+
+// Erratum as listed by channel.software.listErrata
+func (erratum *fakeErratum) listed() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                erratum.ID,
+		"advisory_name":     erratum.Advisory,
+		"advisory_type":     erratum.Type,
+		"advisory_synopsis": erratum.Synopsis,
+		"issue_date":        erratum.Issued,
+		"update_date":       erratum.Issued,
+	}
+}
+
+func (server *FakeServer) emptyList(args fakeArgs) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+func (server *FakeServer) listSoftwareChannels(args fakeArgs) (interface{}, error) {
+	list := make([]interface{}, 0, len(server.state.Channels))
+	for _, channel := range server.state.Channels {
+		list = append(list, channel.listed())
+	}
+	return list, nil
+}
+
+func (server *FakeServer) listChildren(args fakeArgs) (interface{}, error) {
+	if _, err := server.channel(args.str(0)); err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, 0)
+	for _, channel := range server.state.Channels {
+		if channel.ParentLabel == args.str(0) {
+			list = append(list, channel.listed())
+		}
+	}
+	return list, nil
+}
+
+func (server *FakeServer) getDetails(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":                   channel.ID,
+		"label":                channel.Label,
+		"name":                 channel.Name,
+		"summary":              channel.Summary,
+		"description":          channel.Description,
+		"arch_label":           channel.Arch,
+		"arch_name":            channel.Arch,
+		"checksum_label":       channel.Checksum,
+		"parent_channel_label": channel.ParentLabel,
+		"clone_original":       channel.CloneOriginal,
+		"maintainer_name":      channel.Maintainer,
+		"gpg_key_url":          channel.GPGKeyURL,
+		"last_modified":        channel.LastModified,
+		"contentSources":       []interface{}{},
+	}, nil
+}
+
+func (server *FakeServer) setDetails(args fakeArgs) (interface{}, error) {
+	id := args.integer(0)
+	for _, channel := range server.state.Channels {
+		if channel.ID != id {
+			continue
+		}
+		changes := args.structure(1)
+		for key, field := range map[string]*string{"name": &channel.Name, "summary": &channel.Summary,
+			"description": &channel.Description, "checksum_label": &channel.Checksum,
+			"maintainer_name": &channel.Maintainer, "gpg_key_url": &channel.GPGKeyURL} {
+			if _, exist := changes[key]; exist {
+				*field = changes.str(key)
+			}
+		}
+		channel.touch()
+		return 1, nil
+	}
+	return nil, fakeFault(1200, "No such channel: %d", id)
+}
+
+// Add the new channel, checking its label and parent
+func (server *FakeServer) add(channel *fakeChannel) error {
+	if channel.Label == "" {
+		return fakeFault(1201, "Channel label must be specified")
+	}
+	if _, err := server.channel(channel.Label); err == nil {
+		return fakeFault(1201, "Channel label \"%s\" is already in use", channel.Label)
+	}
+	if channel.ParentLabel != "" {
+		parent, err := server.channel(channel.ParentLabel)
+		if err != nil {
+			return err
+		}
+		if parent.ParentLabel != "" {
+			return fakeFault(1201, "Channel \"%s\" is a child channel and cannot be a parent", parent.Label)
+		}
+	}
+	if channel.Name == "" {
+		channel.Name = channel.Label
+	}
+	if channel.Packages == nil {
+		channel.Packages = make([]int, 0)
+	}
+	if channel.Errata == nil {
+		channel.Errata = make([]string, 0)
+	}
+	channel.ID = server.nextID()
+	channel.touch()
+	server.state.Channels = append(server.state.Channels, channel)
+
+	return nil
+}
+
+func (server *FakeServer) create(args fakeArgs) (interface{}, error) {
+	channel := &fakeChannel{
+		Label:       args.str(0),
+		Name:        args.str(1),
+		Summary:     args.str(2),
+		Arch:        args.str(3),
+		ParentLabel: args.str(4),
+		Checksum:    args.str(5),
+	}
+	if err := server.add(channel); err != nil {
+		return nil, err
+	}
+	return 1, nil
+}
+
+func (server *FakeServer) clone(args fakeArgs) (interface{}, error) {
+	original, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	details := args.structure(1)
+	channel := &fakeChannel{
+		Label:         details.str("label"),
+		Name:          details.str("name"),
+		Summary:       details.first("summary", "name"),
+		Description:   details.first("description"),
+		Arch:          details.first("arch_label"),
+		Checksum:      details.first("checksum", "checksum_label"),
+		ParentLabel:   details.first("parent_label", "parent_channel_label"),
+		CloneOriginal: original.Label,
+		Maintainer:    original.Maintainer,
+		GPGKeyURL:     original.GPGKeyURL,
+		Packages:      append([]int{}, original.Packages...),
+		Errata:        make([]string, 0),
+	}
+	if channel.Arch == "" {
+		channel.Arch = original.Arch
+	}
+	if channel.Checksum == "" {
+		channel.Checksum = original.Checksum
+	}
+
+	// Original state has only the packages, which are not brought by errata
+	if args.boolean(2) {
+		for _, advisory := range original.Errata {
+			if erratum, err := server.erratum(advisory); err == nil {
+				channel.removePackages(erratum.Packages)
+			}
+		}
+	} else {
+		channel.Errata = append(channel.Errata, original.Errata...)
+	}
+
+	if err := server.add(channel); err != nil {
+		return nil, err
+	}
+	return channel.ID, nil
+}
+
+func (server *FakeServer) delete(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range server.state.Channels {
+		if other.ParentLabel == channel.Label {
+			return nil, fakeFault(1202, "Channel \"%s\" has child channels and must be deleted after them", channel.Label)
+		}
+	}
+	kept := make([]*fakeChannel, 0, len(server.state.Channels))
+	for _, other := range server.state.Channels {
+		if other != channel {
+			kept = append(kept, other)
+		}
+	}
+	server.state.Channels = kept
+
+	return 1, nil
+}
+
+func (server *FakeServer) listAllPackages(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, 0, len(channel.Packages))
+	for _, id := range channel.Packages {
+		if pkg, err := server.pkg(id); err == nil {
+			list = append(list, pkg.listed())
+		}
+	}
+	return list, nil
+}
+
+func (server *FakeServer) addPackages(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	ids := args.integers(1)
+	for _, id := range ids {
+		if _, err := server.pkg(id); err != nil {
+			return nil, err
+		}
+	}
+	channel.addPackages(ids)
+	channel.touch()
+
+	return 1, nil
+}
+
+func (server *FakeServer) removePackages(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	channel.removePackages(args.integers(1))
+	channel.touch()
+
+	return 1, nil
+}
+
+func (server *FakeServer) mergePackages(args fakeArgs) (interface{}, error) {
+	source, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	target, err := server.channel(args.str(1))
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]interface{}, 0)
+	for _, id := range target.addPackages(source.Packages) {
+		if pkg, err := server.pkg(id); err == nil {
+			merged = append(merged, pkg.listed())
+		}
+	}
+	target.touch()
+
+	return merged, nil
+}
+
+func (server *FakeServer) listErrata(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	since, hasSince := args.date(1)
+	until, hasUntil := args.date(2)
+
+	list := make([]interface{}, 0, len(channel.Errata))
+	for _, advisory := range channel.Errata {
+		erratum, err := server.erratum(advisory)
+		if err != nil {
+			continue
+		}
+		issued, err := time.Parse("2006-01-02", erratum.Issued)
+		if (hasSince || hasUntil) && err != nil {
+			continue
+		}
+		if (hasSince && issued.Before(since)) || (hasUntil && issued.After(until)) {
+			continue
+		}
+		list = append(list, erratum.listed())
+	}
+	return list, nil
+}
+
+func (server *FakeServer) mergeErrata(args fakeArgs) (interface{}, error) {
+	source, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	target, err := server.channel(args.str(1))
+	if err != nil {
+		return nil, err
+	}
+	advisories := source.Errata
+	if len(args) > 2 {
+		advisories = args.strs(2)
+	}
+
+	merged := make([]interface{}, 0)
+	for _, advisory := range advisories {
+		if !source.hasErratum(advisory) || target.hasErratum(advisory) {
+			continue
+		}
+		erratum, err := server.erratum(advisory)
+		if err != nil {
+			return nil, err
+		}
+		target.Errata = append(target.Errata, advisory)
+		target.addPackages(erratum.Packages)
+		merged = append(merged, erratum.listed())
+	}
+	target.touch()
+
+	return merged, nil
+}
+
+func (server *FakeServer) removeErrata(args fakeArgs) (interface{}, error) {
+	channel, err := server.channel(args.str(0))
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]bool)
+	for _, advisory := range args.strs(1) {
+		removed[advisory] = true
+		if erratum, err := server.erratum(advisory); err == nil && args.boolean(2) {
+			channel.removePackages(erratum.Packages)
+		}
+	}
+	kept := make([]string, 0, len(channel.Errata))
+	for _, advisory := range channel.Errata {
+		if !removed[advisory] {
+			kept = append(kept, advisory)
+		}
+	}
+	channel.Errata = kept
+	channel.touch()
+
+	return 1, nil
+}
+
+func (server *FakeServer) findByNvrea(args fakeArgs) (interface{}, error) {
+	list := make([]interface{}, 0)
+	for _, pkg := range server.state.Packages {
+		if pkg.Name == args.str(0) && pkg.Version == args.str(1) && pkg.Release == args.str(2) &&
+			pkg.Epoch == args.str(3) && pkg.Arch == args.str(4) {
+			list = append(list, pkg.listed())
+		}
+	}
+	return list, nil
+}
+
+func (server *FakeServer) packageDetails(args fakeArgs) (interface{}, error) {
+	pkg, err := server.pkg(args.integer(0))
+	if err != nil {
+		return nil, err
+	}
+	return pkg.listed(), nil
+}
+
+func (server *FakeServer) applicableToChannels(args fakeArgs) (interface{}, error) {
+	if _, err := server.erratum(args.str(0)); err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, 0)
+	for _, channel := range server.state.Channels {
+		if channel.hasErratum(args.str(0)) {
+			list = append(list, map[string]interface{}{
+				"channel_id":           channel.ID,
+				"label":                channel.Label,
+				"name":                 channel.Name,
+				"parent_channel_label": channel.ParentLabel,
+			})
+		}
+	}
+	return list, nil
+}
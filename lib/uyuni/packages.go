@@ -0,0 +1,109 @@
+package uyuni
+
+// FindPackages returns packages with exactly the same NEVRA
+func (client *Client) FindPackages(name string, version string, release string, epoch string, arch string) ([]Package, error) {
+	records, err := client.callList("packages.findByNvrea", name, version, release, epoch, arch)
+	if err != nil {
+		return nil, err
+	}
+	return client.packages(records), nil
+}
+
+// PackageDetails returns details of the package, including its checksum
+func (client *Client) PackageDetails(id int) (*Package, error) {
+	data, err := client.callStruct("packages.getDetails", id)
+	if err != nil {
+		return nil, err
+	}
+	pkg := newPackage(data)
+	return &pkg, nil
+}
+
+// ErratumChannels returns channels, which provide the erratum
+func (client *Client) ErratumChannels(advisory string) ([]Channel, error) {
+	records, err := client.callList("errata.applicableToChannels", advisory)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, 0, len(records))
+	for _, data := range records {
+		channels = append(channels, newChannel(data))
+	}
+	return channels, nil
+}
+
+// Decode list of packages
+func (client *Client) packages(records []record) []Package {
+	packages := make([]Package, 0, len(records))
+	for _, data := range records {
+		packages = append(packages, newPackage(data))
+	}
+	return packages
+}
+
+// SearchPackages returns packages with the name, matching the query. Advanced query is in Lucene syntax.
+func (client *Client) SearchPackages(query string, advanced bool) ([]Package, error) {
+	method := "packages.search.name"
+	if advanced {
+		method = "packages.search.advanced"
+	}
+	records, err := client.callList(method, query)
+	if err != nil {
+		return nil, err
+	}
+	return client.packages(records), nil
+}
+
+// PackageChannels returns channels, which provide the package
+func (client *Client) PackageChannels(id int) ([]Channel, error) {
+	records, err := client.callList("packages.listProvidingChannels", id)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, 0, len(records))
+	for _, data := range records {
+		channels = append(channels, newChannel(data))
+	}
+	return channels, nil
+}
+
+// PackageErrata returns errata, which provide the package
+func (client *Client) PackageErrata(id int) ([]Erratum, error) {
+	records, err := client.callList("packages.listProvidingErrata", id)
+	if err != nil {
+		return nil, err
+	}
+	return client.errata(records), nil
+}
+
+// ErratumDetails returns details of the erratum
+func (client *Client) ErratumDetails(advisory string) (*ErratumDetails, error) {
+	data, err := client.callStruct("errata.getDetails", advisory)
+	if err != nil {
+		return nil, err
+	}
+	return newErratumDetails(advisory, data), nil
+}
+
+// ErratumCVEs returns CVEs, fixed by the erratum
+func (client *Client) ErratumCVEs(advisory string) ([]string, error) {
+	return client.callStrings("errata.listCves", advisory)
+}
+
+// ErratumPackages returns packages of the erratum with the channels providing them
+func (client *Client) ErratumPackages(advisory string) ([]Package, error) {
+	records, err := client.callList("errata.listPackages", advisory)
+	if err != nil {
+		return nil, err
+	}
+	return client.packages(records), nil
+}
+
+// FindErrataByCVE returns errata, which fix the CVE
+func (client *Client) FindErrataByCVE(cve string) ([]Erratum, error) {
+	records, err := client.callList("errata.findByCve", cve)
+	if err != nil {
+		return nil, err
+	}
+	return client.errata(records), nil
+}
@@ -0,0 +1,57 @@
+package uyuni
+
+import (
+	"github.com/isbm/spaceman/lib/utils"
+	"sort"
+)
+
+// ListSystems returns all systems, visible to the user
+func (client *Client) ListSystems() ([]System, error) {
+	records, err := client.callList("system.listSystems")
+	if err != nil {
+		return nil, err
+	}
+	systems := make([]System, 0, len(records))
+	for _, data := range records {
+		systems = append(systems, newSystem(data))
+	}
+	return systems, nil
+}
+
+// SubscribedChannels returns base channel (empty, if there is none) and sorted child channels of the system
+func (client *Client) SubscribedChannels(sid int) (string, []string, error) {
+	base, err := client.callStruct("system.getSubscribedBaseChannel", sid)
+	if fault, ok := utils.AsFault(err); ok && fault.IsNotFound() {
+		base, err = record{}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	records, err := client.callList("system.listSubscribedChildChannels", sid)
+	if err != nil {
+		return "", nil, err
+	}
+	children := make([]string, 0, len(records))
+	for _, data := range records {
+		if label := data.str("label"); label != "" {
+			children = append(children, label)
+		}
+	}
+	sort.Strings(children)
+
+	return base.str("label"), children, nil
+}
+
+// CVEPatchStatus returns patch status of all systems, relevant for the CVE
+func (client *Client) CVEPatchStatus(cve string) ([]PatchStatus, error) {
+	records, err := client.callList("audit.listSystemsByPatchStatus", cve)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]PatchStatus, 0, len(records))
+	for _, data := range records {
+		statuses = append(statuses, newPatchStatus(data))
+	}
+	return statuses, nil
+}
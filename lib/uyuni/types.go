@@ -0,0 +1,235 @@
+package uyuni
+
+import (
+	"fmt"
+)
+
+// Channel as listed by channel.listSoftwareChannels
+type Channel struct {
+	Label       string
+	Name        string
+	ParentLabel string
+	Arch        string
+	EndOfLife   string
+}
+
+// IsBase tells if the channel has no parent
+func (channel Channel) IsBase() bool {
+	return channel.ParentLabel == ""
+}
+
+func newChannel(data record) Channel {
+	return Channel{
+		Label:       data.str("label"),
+		Name:        data.str("name"),
+		ParentLabel: data.first("parent_label", "parent_channel_label"),
+		Arch:        data.first("arch", "arch_label"),
+		EndOfLife:   data.str("end_of_life"),
+	}
+}
+
+// ChannelDetails as returned by channel.software.getDetails
+type ChannelDetails struct {
+	ID             int
+	Label          string
+	Name           string
+	Summary        string
+	Description    string
+	ArchLabel      string
+	ArchName       string
+	ChecksumLabel  string
+	ParentLabel    string
+	CloneOriginal  string
+	Maintainer     string
+	GPGKeyURL      string
+	LastModified   string
+	LastSync       string
+	ContentSources []ContentSource
+	// Raw response with all fields, including those unknown to the client
+	Raw map[string]interface{}
+}
+
+func newChannelDetails(data record) *ChannelDetails {
+	details := &ChannelDetails{
+		ID:             data.integer("id"),
+		Label:          data.str("label"),
+		Name:           data.str("name"),
+		Summary:        data.str("summary"),
+		Description:    data.str("description"),
+		ArchLabel:      data.str("arch_label"),
+		ArchName:       data.str("arch_name"),
+		ChecksumLabel:  data.str("checksum_label"),
+		ParentLabel:    data.str("parent_channel_label"),
+		CloneOriginal:  data.str("clone_original"),
+		Maintainer:     data.str("maintainer_name"),
+		GPGKeyURL:      data.str("gpg_key_url"),
+		LastModified:   data.str("last_modified"),
+		LastSync:       data.str("yumrepo_last_sync"),
+		ContentSources: make([]ContentSource, 0),
+		Raw:            data,
+	}
+	for _, source := range data.records("contentSources") {
+		details.ContentSources = append(details.ContentSources, newContentSource(source))
+	}
+	return details
+}
+
+// Fields of the channel details by their API names. Empty values are nil.
+func (details *ChannelDetails) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"id":                   details.ID,
+		"label":                details.Label,
+		"name":                 details.Name,
+		"summary":              details.Summary,
+		"description":          details.Description,
+		"arch_label":           details.ArchLabel,
+		"arch_name":            details.ArchName,
+		"checksum_label":       details.ChecksumLabel,
+		"parent_channel_label": details.ParentLabel,
+		"clone_original":       details.CloneOriginal,
+		"maintainer_name":      details.Maintainer,
+		"gpg_key_url":          details.GPGKeyURL,
+		"last_modified":        details.LastModified,
+		"yumrepo_last_sync":    details.LastSync,
+	}
+	for key, value := range fields {
+		if value == "" {
+			fields[key] = nil
+		}
+	}
+	return fields
+}
+
+// ContentSource is a repository, associated with the channel
+type ContentSource struct {
+	ID    int
+	Label string
+	Type  string
+	URL   string
+	// Raw response with all fields, including those unknown to the client
+	Raw map[string]interface{}
+}
+
+func newContentSource(data record) ContentSource {
+	return ContentSource{
+		ID:    data.integer("id"),
+		Label: data.str("label"),
+		Type:  data.str("type"),
+		URL:   data.first("sourceUrl", "url"),
+		Raw:   data,
+	}
+}
+
+// Package in the channel
+type Package struct {
+	ID           int
+	Name         string
+	Epoch        string
+	Version      string
+	Release      string
+	Arch         string
+	Checksum     string
+	ChecksumType string
+	// Channels providing the package, if the response lists them
+	Channels []string
+}
+
+func newPackage(data record) Package {
+	return Package{
+		ID:           data.integer("id"),
+		Name:         data.str("name"),
+		Epoch:        data.str("epoch"),
+		Version:      data.str("version"),
+		Release:      data.str("release"),
+		Arch:         data.first("arch_label", "arch"),
+		Checksum:     data.str("checksum"),
+		ChecksumType: data.str("checksum_type"),
+		Channels:     data.strs("providing_channels"),
+	}
+}
+
+// NEVRA of the package: name-[epoch:]version-release.arch
+func (pkg Package) String() string {
+	epoch := ""
+	if pkg.Epoch != "" {
+		epoch = pkg.Epoch + ":"
+	}
+	nevra := fmt.Sprintf("%s-%s%s-%s", pkg.Name, epoch, pkg.Version, pkg.Release)
+	if pkg.Arch != "" {
+		nevra += "." + pkg.Arch
+	}
+	return nevra
+}
+
+// Erratum in the channel
+type Erratum struct {
+	ID       int
+	Advisory string
+	Type     string
+	Synopsis string
+	Issued   string
+	Updated  string
+	Severity string
+}
+
+func newErratum(data record) Erratum {
+	return Erratum{
+		ID:       data.integer("id"),
+		Advisory: data.first("advisory_name", "advisory"),
+		Type:     data.first("advisory_type", "type"),
+		Synopsis: data.first("advisory_synopsis", "synopsis"),
+		Issued:   data.first("issue_date", "date"),
+		Updated:  data.first("update_date", "last_modified_date"),
+		Severity: data.str("severity"),
+	}
+}
+
+// ErratumDetails as returned by errata.getDetails
+type ErratumDetails struct {
+	Erratum
+	// Raw response with all fields, including those unknown to the client
+	Raw map[string]interface{}
+}
+
+func newErratumDetails(advisory string, data record) *ErratumDetails {
+	details := &ErratumDetails{Erratum: newErratum(data), Raw: data}
+	if details.Advisory == "" {
+		details.Advisory = advisory
+	}
+	return details
+}
+
+// PatchStatus of the system for a CVE, as returned by audit.listSystemsByPatchStatus
+type PatchStatus struct {
+	SystemID   int
+	Status     string
+	Channels   []string
+	Advisories []string
+}
+
+// IsAffected tells if the system still needs a patch
+func (status PatchStatus) IsAffected() bool {
+	return status.Status != "NOT_AFFECTED" && status.Status != "PATCHED"
+}
+
+func newPatchStatus(data record) PatchStatus {
+	return PatchStatus{
+		SystemID:   data.integer("system_id"),
+		Status:     data.str("patch_status"),
+		Channels:   data.strs("channel_labels"),
+		Advisories: data.strs("errata_advisories"),
+	}
+}
+
+// System, subscribed to the channel
+type System struct {
+	ID   int
+	Name string
+}
+
+func newSystem(data record) System {
+	return System{
+		ID:   data.integer("id"),
+		Name: data.str("name"),
+	}
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"github.com/isbm/spaceman/lib/app_activationkeys"
+	"github.com/isbm/spaceman/lib/app_apply"
+	"github.com/isbm/spaceman/lib/app_audit"
+	"github.com/isbm/spaceman/lib/app_config"
+	"github.com/isbm/spaceman/lib/app_export"
 	"github.com/isbm/spaceman/lib/app_info"
 	"github.com/isbm/spaceman/lib/app_lifecycle"
+	"github.com/isbm/spaceman/lib/app_repos"
+	"github.com/isbm/spaceman/lib/app_session"
+	"github.com/isbm/spaceman/lib/app_systems"
 	"github.com/isbm/spaceman/lib/utils"
 	"github.com/urfave/cli"
 	"log"
@@ -17,7 +25,12 @@ func main() {
 		cli.StringFlag{
 			Name:  "config",
 			Value: utils.Configuration.GetDefaultConfigFile(),
-			Usage: "Configuration file",
+			Usage: "User configuration file, merged over /etc/rhn/spaceman.conf and under .spaceman.conf of the project",
+		},
+		cli.StringFlag{
+			Name:   "server",
+			Usage:  "Use named server from the \"servers\" configuration section",
+			EnvVar: "SPACEMAN_SERVER",
 		},
 		cli.BoolFlag{
 			Name:   "V, verbose",
@@ -29,9 +42,62 @@ func main() {
 			Usage:  "Turn off entire logging (no errors either), only standard messages, if any",
 			Hidden: false,
 		},
+		cli.StringFlag{
+			Name:   "log-file",
+			Usage:  "Append log messages to the `file` instead of STDERR",
+			EnvVar: "SPACEMAN_LOG_FILE",
+		},
+		cli.StringFlag{
+			Name:  "log-format",
+			Value: "text",
+			Usage: "Format of log messages: text or json",
+		},
+		cli.BoolFlag{
+			Name:   "no-cache",
+			Usage:  "Always call the server, neither reading nor writing cached responses",
+			EnvVar: "SPACEMAN_NO_CACHE",
+		},
+		cli.BoolFlag{
+			Name:  "refresh",
+			Usage: "Call the server and replace cached responses",
+		},
+		cli.StringFlag{
+			Name:  "record",
+			Usage: "Store every call and response of the server in the `directory`, with session and password redacted",
+		},
+		cli.StringFlag{
+			Name:  "replay",
+			Usage: "Serve calls from the responses, recorded in the `directory`, instead of the server",
+		},
+		cli.StringFlag{
+			Name:   "audit-log",
+			Usage:  "Append records of changing operations to the `file` instead of ~/.local/share/spaceman/audit.log",
+			EnvVar: "SPACEMAN_AUDIT_LOG",
+		},
+	}
+	app.Before = func(ctx *cli.Context) error {
+		if ctx.GlobalBool("quiet") && ctx.GlobalBool("verbose") {
+			utils.Console.ExitOnUnknown("Don't know how to be quietly verbose.")
+		}
+		utils.Console.CheckError(utils.Log.Configure(ctx.GlobalBool("verbose"), ctx.GlobalBool("quiet"),
+			ctx.GlobalString("log-format"), ctx.GlobalString("log-file")))
+		utils.SetCacheMode(ctx.GlobalBool("no-cache"), ctx.GlobalBool("refresh"))
+		utils.Console.CheckError(utils.SetRecording(ctx.GlobalString("record"), ctx.GlobalString("replay")))
+		utils.Audit.SetFile(ctx.GlobalString("audit-log"))
+		return nil
 	}
 
 	app.Commands = []cli.Command{
+		{
+			Name:   "login",
+			Usage:  "Log in to the server and store the session",
+			Action: app_session.MainLoginCmd,
+		},
+		{
+			Name:   "logout",
+			Usage:  "Log out from the server and remove the stored session",
+			Action: app_session.MainLogoutCmd,
+		},
 		{
 			Name:    "lifecycle",
 			Aliases: []string{"lc"},
@@ -46,6 +112,64 @@ func main() {
 			Action:  app_info.MainInfoCmd,
 			Flags:   app_info.InfoCmdFlags,
 		},
+		{
+			Name:    "systems",
+			Aliases: []string{"sys"},
+			Usage:   "Manage channel subscriptions of systems",
+			Action:  app_systems.MainSystemsCmd,
+			Flags:   app_systems.SystemsCmdFlags,
+		},
+		{
+			Name:    "activationkeys",
+			Aliases: []string{"ak"},
+			Usage:   "Manage activation keys and their channels",
+			Action:  app_activationkeys.MainActivationKeysCmd,
+			Flags:   app_activationkeys.ActivationKeysCmdFlags,
+		},
+		{
+			Name:    "repos",
+			Aliases: []string{"rp"},
+			Usage:   "Manage repositories (content sources) of channels",
+			Action:  app_repos.MainReposCmd,
+			Flags:   app_repos.ReposCmdFlags,
+		},
+		{
+			Name:      "apply",
+			Usage:     "Make channels on the server match the declared ones",
+			ArgsUsage: "channels.yaml",
+			Action:    app_apply.MainApplyCmd,
+			Flags:     app_apply.ApplyCmdFlags,
+		},
+		{
+			Name:   "export",
+			Usage:  "Export channel tree metadata, packages and errata to a bundle",
+			Action: app_export.MainExportCmd,
+			Flags:  app_export.ExportCmdFlags,
+		},
+		{
+			Name:      "import",
+			Usage:     "Recreate or re-merge channel content from a bundle",
+			ArgsUsage: "bundle.json",
+			Action:    app_export.MainImportCmd,
+			Flags:     app_export.ImportCmdFlags,
+		},
+		{
+			Name:   "audit",
+			Usage:  "Show recorded operations, which changed channels or subscriptions",
+			Action: app_audit.MainAuditCmd,
+			Flags:  app_audit.AuditCmdFlags,
+		},
+		{
+			Name:  "config",
+			Usage: "Inspect configuration",
+			Subcommands: []cli.Command{
+				{
+					Name:   "show",
+					Usage:  "Show effective configuration with sources of the options, secrets are masked",
+					Action: app_config.MainConfigShowCmd,
+				},
+			},
+		},
 	}
 
 	err := app.Run(os.Args)